@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/M-Sviridov/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// revokeAccessToken denylists the access token the caller authenticated with
+// until it would have expired anyway.
+func (cfg *apiConfig) revokeAccessToken(ctx context.Context, p principal) error {
+	if p.tokenID == "" {
+		return nil
+	}
+
+	_, err := cfg.db.RevokeAccessToken(ctx, database.RevokeAccessTokenParams{
+		Jti:       p.tokenID,
+		UserID:    p.userID,
+		ExpiresAt: p.tokenExpiresAt,
+	})
+	return err
+}
+
+// revokeUserAccessTokens invalidates every access token issued to the user so
+// far. JWT issue times have millisecond precision, so the watermark is
+// truncated to match, and tokens issued in the same millisecond are
+// rejected along with the older ones.
+func revokeUserAccessTokens(ctx context.Context, q *database.Queries, userID uuid.UUID) error {
+	return q.RevokeUserAccessTokens(ctx, database.RevokeUserAccessTokensParams{
+		TokensRevokedBefore: time.Now().UTC().Truncate(time.Millisecond),
+		ID:                  userID,
+	})
+}
+
+// purgeRevokedAccessTokens removes denylist entries for access tokens that
+// have expired on their own.
+func (cfg *apiConfig) purgeRevokedAccessTokens(ctx context.Context) error {
+	return cfg.db.DeleteExpiredRevokedAccessTokens(ctx, time.Now().UTC())
+}
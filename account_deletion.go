@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/M-Sviridov/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Users who delete their account get a grace period to change their mind.
+// Until it's over they're signed out and their chirps are hidden, and
+// logging back in cancels the deletion. After it, the account and
+// everything it owns is deleted for good.
+const defaultAccountDeletionGraceDays = 30
+
+// accountDeletionGraceDaysFromEnv reads how many days an account scheduled
+// for deletion can still be recovered for.
+func accountDeletionGraceDaysFromEnv() (int32, error) {
+	v := os.Getenv("ACCOUNT_DELETION_GRACE_PERIOD_DAYS")
+	if v == "" {
+		return defaultAccountDeletionGraceDays, nil
+	}
+	days, err := strconv.ParseInt(v, 10, 32)
+	if err != nil || days < 0 {
+		return 0, fmt.Errorf("invalid ACCOUNT_DELETION_GRACE_PERIOD_DAYS %q", v)
+	}
+	return int32(days), nil
+}
+
+// cancelAccountDeletion takes a user who logged back in off the deletion
+// schedule, if they were on it.
+func (cfg *apiConfig) cancelAccountDeletion(r *http.Request, user database.User) error {
+	if !user.DeletionScheduledFor.Valid {
+		return nil
+	}
+
+	canceled, err := cfg.db.CancelUserDeletion(r.Context(), user.ID)
+	if err != nil {
+		return err
+	}
+	if canceled > 0 {
+		cfg.recordAuditEvent(r, userAuditEvent(auditAccountDeletionCanceled, user.ID, nil))
+	}
+	return nil
+}
+
+// deleteScheduledAccounts deletes the accounts whose grace period is over.
+// Everything they own goes with them.
+func (cfg *apiConfig) deleteScheduledAccounts(ctx context.Context) error {
+	userIDs, err := cfg.db.DeleteUsersScheduledForDeletion(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, userID := range userIDs {
+		log.Printf("deleted account %s", userID)
+	}
+	return nil
+}
+
+// revokeAllCredentials signs a user out everywhere and revokes their
+// personal access tokens.
+func (cfg *apiConfig) revokeAllCredentials(ctx context.Context, userID uuid.UUID) error {
+	if err := cfg.revokeAllSessions(ctx, userID); err != nil {
+		return err
+	}
+	return cfg.db.RevokeAllPersonalAccessTokens(ctx, userID)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/M-Sviridov/chirpy/internal/auth"
+	"github.com/M-Sviridov/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// principal is the authenticated caller of a request.
+type principal struct {
+	userID         uuid.UUID
+	sessionID      uuid.UUID
+	tokenID        string
+	tokenExpiresAt time.Time
+	emailVerified  bool
+	// role is only set for the user's own sessions.
+	role string
+	// personalAccessTokenID is set when the caller used a personal access
+	// token instead of a session's access token.
+	personalAccessTokenID uuid.UUID
+	// clientID is set when the caller is an OAuth client acting on the
+	// user's behalf.
+	clientID uuid.UUID
+}
+
+// delegated reports whether the caller only holds a scoped grant of the
+// user's access rather than one of the user's own sessions.
+func (p principal) delegated() bool {
+	return p.personalAccessTokenID != uuid.Nil || p.clientID != uuid.Nil
+}
+
+// authenticate resolves the caller from the request's bearer token, or from
+// its session cookies for browsers, which must also pass the CSRF check.
+// Session access tokens are always accepted; personal access tokens and
+// OAuth clients' access tokens only if they were granted scope, and never
+// when scope is empty, which marks endpoints that manage the account's
+// credentials. It writes an error response and returns false if the caller
+// couldn't be authenticated.
+func (cfg *apiConfig) authenticate(w http.ResponseWriter, r *http.Request, scope string) (principal, bool) {
+	bearerToken, err := auth.GetBearerToken(r.Header)
+	fromCookie := false
+	if err != nil {
+		// Without an Authorization header, fall back to a browser session.
+		bearerToken, err = cfg.cookieAccessToken(w, r)
+		if errors.Is(err, errNoSessionCookie) {
+			respondWithError(w, http.StatusUnauthorized, "couldn't get bearer token")
+			return principal{}, false
+		}
+		if err != nil {
+			cfg.clearSessionCookies(w)
+			respondWithError(w, http.StatusUnauthorized, "session has expired")
+			return principal{}, false
+		}
+		fromCookie = true
+	}
+
+	var p principal
+	var user database.User
+	var ok bool
+	if auth.IsPersonalAccessToken(bearerToken) {
+		p, user, ok = cfg.authenticatePersonalAccessToken(w, r, bearerToken, scope)
+	} else {
+		p, user, ok = cfg.authenticateAccessToken(w, r, bearerToken, scope)
+	}
+	if !ok {
+		return principal{}, false
+	}
+
+	if fromCookie && !cfg.checkCSRF(r, p.sessionID) {
+		respondWithError(w, http.StatusForbidden, "missing or invalid CSRF token")
+		return principal{}, false
+	}
+
+	if rejectLockedOutUser(w, user) {
+		return principal{}, false
+	}
+
+	p.emailVerified = user.EmailVerifiedAt.Valid
+	if !p.emailVerified && cfg.unverifiedAccess == unverifiedAccessNone {
+		respondWithError(w, http.StatusForbidden, "email address must be verified first")
+		return principal{}, false
+	}
+
+	return p, true
+}
+
+func (cfg *apiConfig) authenticateAccessToken(w http.ResponseWriter, r *http.Request, accessToken, scope string) (principal, database.User, bool) {
+	claims, err := auth.ParseJWT(accessToken, cfg.jwtKeys)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "JWT token is invalid")
+		return principal{}, database.User{}, false
+	}
+
+	if claims.ClientID != "" {
+		if scope == "" {
+			respondWithError(w, http.StatusForbidden, "OAuth clients can't use this endpoint")
+			return principal{}, database.User{}, false
+		}
+		if !hasScope(claims.Scopes(), scope) {
+			respondWithError(w, http.StatusForbidden, fmt.Sprintf("access token is missing the %s scope", scope))
+			return principal{}, database.User{}, false
+		}
+	}
+
+	p := principal{
+		userID:         uuid.MustParse(claims.Subject),
+		tokenID:        claims.ID,
+		tokenExpiresAt: claims.ExpiresAt.Time,
+	}
+	if claims.SessionID != "" {
+		p.sessionID = uuid.MustParse(claims.SessionID)
+	}
+	if claims.ClientID != "" {
+		p.clientID = uuid.MustParse(claims.ClientID)
+	}
+
+	if claims.ID != "" {
+		revoked, err := cfg.db.IsAccessTokenRevoked(r.Context(), claims.ID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "couldn't check JWT token revocation")
+			return principal{}, database.User{}, false
+		}
+		if revoked {
+			respondWithError(w, http.StatusUnauthorized, "JWT token has been revoked")
+			return principal{}, database.User{}, false
+		}
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), p.userID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "couldn't get user from JWT token")
+		return principal{}, database.User{}, false
+	}
+	if user.TokensRevokedBefore.Valid && !claims.IssuedAt.Time.After(user.TokensRevokedBefore.Time) {
+		respondWithError(w, http.StatusUnauthorized, "JWT token has been revoked")
+		return principal{}, database.User{}, false
+	}
+
+	// A promotion takes effect when the token is next refreshed, but a
+	// demotion takes effect straight away.
+	if claims.ClientID == "" {
+		p.role = lowerRole(claims.Role, user.Role)
+	}
+
+	return p, user, true
+}
+
+func (cfg *apiConfig) authenticatePersonalAccessToken(w http.ResponseWriter, r *http.Request, token, scope string) (principal, database.User, bool) {
+	if scope == "" {
+		respondWithError(w, http.StatusForbidden, "personal access tokens can't be used for this endpoint")
+		return principal{}, database.User{}, false
+	}
+
+	pat, err := cfg.db.GetActivePersonalAccessToken(r.Context(), auth.HashToken(token, cfg.tokenHashKey))
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "personal access token is invalid")
+		return principal{}, database.User{}, false
+	}
+	if !hasScope(pat.Scopes, scope) {
+		respondWithError(w, http.StatusForbidden, fmt.Sprintf("personal access token is missing the %s scope", scope))
+		return principal{}, database.User{}, false
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), pat.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "couldn't get user from personal access token")
+		return principal{}, database.User{}, false
+	}
+
+	if err := cfg.db.TouchPersonalAccessToken(r.Context(), pat.ID); err != nil {
+		log.Printf("error updating personal access token last use: %s", err)
+	}
+
+	p := principal{
+		userID:                user.ID,
+		tokenExpiresAt:        pat.ExpiresAt,
+		personalAccessTokenID: pat.ID,
+	}
+	return p, user, true
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/M-Sviridov/chirpy/internal/auth"
+	"github.com/M-Sviridov/chirpy/internal/database"
+)
+
+// runCommand runs one of the administrative subcommands of the chirpy
+// binary instead of the server.
+func runCommand(name string, args []string) error {
+	switch name {
+	case "calibrate":
+		return runCalibrate(args)
+	case "bootstrap-admin":
+		return runBootstrapAdmin(args)
+	default:
+		return fmt.Errorf("unknown command %q", name)
+	}
+}
+
+// runCalibrate prints the argon2id parameters that make hashing a password
+// take the target time on this machine.
+func runCalibrate(args []string) error {
+	flags := flag.NewFlagSet("calibrate", flag.ContinueOnError)
+	target := flags.Duration("target", 250*time.Millisecond, "time one password hash should take")
+	memory := flags.Uint("memory", 64*1024, "memory to use in KiB")
+	parallelism := flags.Uint("parallelism", uint(min(runtime.NumCPU(), 255)), "number of threads")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *parallelism == 0 || *parallelism > 255 {
+		return fmt.Errorf("parallelism must be between 1 and 255")
+	}
+
+	params, elapsed, err := auth.CalibratePasswordParams(*target, uint32(*memory), uint8(*parallelism))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("# one hash takes %s\n", elapsed.Round(time.Millisecond))
+	fmt.Printf("ARGON2_MEMORY=%d\n", params.Memory)
+	fmt.Printf("ARGON2_ITERATIONS=%d\n", params.Iterations)
+	fmt.Printf("ARGON2_PARALLELISM=%d\n", params.Parallelism)
+
+	return nil
+}
+
+// runBootstrapAdmin makes the first admin, so the admin endpoints can be
+// used without ever relying on the dev platform. An existing account with
+// the email is promoted; otherwise one is created with a password read
+// from standard input. It refuses to run once an admin exists.
+func runBootstrapAdmin(args []string) error {
+	flags := flag.NewFlagSet("bootstrap-admin", flag.ContinueOnError)
+	email := flags.String("email", "", "email address of the admin")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := validateEmail(*email); err != nil {
+		return fmt.Errorf("-email: %w", err)
+	}
+
+	dbURL := os.Getenv("DB_URL")
+	if dbURL == "" {
+		return errors.New("DB_URL cannot be empty")
+	}
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		return fmt.Errorf("error opening database: %w", err)
+	}
+	defer db.Close()
+
+	cfg := &apiConfig{
+		db:     database.New(db),
+		dbConn: db,
+	}
+	ctx := context.Background()
+
+	admins, err := cfg.db.CountUsersWithRole(ctx, roleAdmin)
+	if err != nil {
+		return err
+	}
+	if admins > 0 {
+		return errors.New("an admin already exists; manage roles through the admin API instead")
+	}
+
+	user, err := cfg.db.GetUserByEmail(ctx, *email)
+	if errors.Is(err, sql.ErrNoRows) {
+		user, err = cfg.bootstrapUser(ctx, *email)
+	}
+	if err != nil {
+		return err
+	}
+
+	roleParams := database.SetUserRoleParams{
+		ID:   user.ID,
+		Role: roleAdmin,
+	}
+	if err := cfg.db.SetUserRole(ctx, roleParams); err != nil {
+		return err
+	}
+
+	fmt.Printf("%s is now an admin\n", user.Email)
+	return nil
+}
+
+// bootstrapUser creates a verified account for the first admin.
+func (cfg *apiConfig) bootstrapUser(ctx context.Context, email string) (database.User, error) {
+	passwordParams, err := passwordParamsFromEnv()
+	if err != nil {
+		return database.User{}, err
+	}
+	passwordPolicy, err := passwordPolicyFromEnv()
+	if err != nil {
+		return database.User{}, err
+	}
+
+	fmt.Fprintf(os.Stderr, "No account uses %s yet; enter a password for it: ", email)
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		return database.User{}, fmt.Errorf("error reading password: %w", err)
+	}
+	password = strings.TrimRight(password, "\r\n")
+
+	if violations := passwordPolicy.CheckPassword(password, auth.EmailContextWords(email)...); len(violations) > 0 {
+		return database.User{}, fmt.Errorf("password rejected: %s", violations[0].Message)
+	}
+
+	hash, err := auth.HashPassword(password, passwordParams)
+	if err != nil {
+		return database.User{}, err
+	}
+
+	var user database.User
+	err = cfg.withTx(ctx, func(q *database.Queries) error {
+		created, err := q.CreateUser(ctx, database.CreateUserParams{
+			Email:          email,
+			HashedPassword: hash,
+		})
+		if err != nil {
+			return err
+		}
+
+		user, err = q.VerifyUserEmail(ctx, database.VerifyUserEmailParams{
+			Email: created.Email,
+			ID:    created.ID,
+		})
+		return err
+	})
+
+	return user, err
+}
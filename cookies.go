@@ -0,0 +1,109 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+
+	"github.com/M-Sviridov/chirpy/internal/auth"
+	"github.com/google/uuid"
+)
+
+// Browser sessions keep their tokens in HttpOnly cookies, out of reach of
+// scripts. The CSRF cookie is the exception: the app reads it and echoes it
+// in the CSRF header of every state-changing request.
+const (
+	accessTokenCookie  = "chirpy_access"
+	refreshTokenCookie = "chirpy_refresh"
+	csrfTokenCookie    = "chirpy_csrf"
+	csrfTokenHeader    = "X-CSRF-Token"
+)
+
+var errNoSessionCookie = errors.New("no session cookie")
+
+// csrfToken returns the CSRF token of a session. It's derived from the
+// session ID with the server's key, so it can't be guessed or planted by
+// another site, and needs no storage.
+func (cfg *apiConfig) csrfToken(sessionID uuid.UUID) string {
+	return auth.HashToken("csrf:"+sessionID.String(), cfg.tokenHashKey)
+}
+
+func (cfg *apiConfig) sessionCookie(name, value string, maxAge int, httpOnly bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: httpOnly,
+		Secure:   cfg.secureCookies,
+		SameSite: http.SameSiteStrictMode,
+	}
+}
+
+// setSessionCookies hands a new session to a browser.
+func (cfg *apiConfig) setSessionCookies(w http.ResponseWriter, sess session) {
+	cfg.setAccessTokenCookie(w, sess.accessToken)
+	http.SetCookie(w, cfg.sessionCookie(refreshTokenCookie, sess.refreshToken, int(refreshTokenExpiry.Seconds()), true))
+	http.SetCookie(w, cfg.sessionCookie(csrfTokenCookie, cfg.csrfToken(sess.id), int(refreshTokenExpiry.Seconds()), false))
+}
+
+func (cfg *apiConfig) setAccessTokenCookie(w http.ResponseWriter, accessToken string) {
+	http.SetCookie(w, cfg.sessionCookie(accessTokenCookie, accessToken, int(accessTokenExpiry.Seconds()), true))
+}
+
+func (cfg *apiConfig) clearSessionCookies(w http.ResponseWriter) {
+	for _, name := range []string{accessTokenCookie, refreshTokenCookie, csrfTokenCookie} {
+		http.SetCookie(w, cfg.sessionCookie(name, "", -1, name != csrfTokenCookie))
+	}
+}
+
+// cookieAccessToken returns the access token of a browser session. If the
+// access token cookie has expired but the refresh token cookie is still
+// good, a new access token is issued and set on w.
+func (cfg *apiConfig) cookieAccessToken(w http.ResponseWriter, r *http.Request) (string, error) {
+	if c, err := r.Cookie(accessTokenCookie); err == nil {
+		if _, err := auth.ParseJWT(c.Value, cfg.jwtKeys); err == nil {
+			return c.Value, nil
+		}
+	}
+
+	c, err := r.Cookie(refreshTokenCookie)
+	if err != nil {
+		return "", errNoSessionCookie
+	}
+
+	rt, err := cfg.getActiveRefreshToken(r.Context(), c.Value)
+	if err != nil || rt.ClientID.Valid {
+		return "", errors.New("refresh token is invalid or has expired")
+	}
+
+	if err := cfg.db.TouchRefreshToken(r.Context(), rt.ID); err != nil {
+		return "", err
+	}
+
+	cfg.recordAuditEvent(r, userAuditEvent(auditTokenRefreshed, rt.UserID, map[string]any{
+		"session_id": rt.ID,
+		"cookies":    true,
+	}))
+
+	accessToken, err := cfg.makeSessionJWT(r.Context(), rt)
+	if err != nil {
+		return "", err
+	}
+
+	cfg.setAccessTokenCookie(w, accessToken)
+	return accessToken, nil
+}
+
+// checkCSRF reports whether a request authenticated by session cookies
+// may go ahead. Safe methods always may; others must carry the session's
+// CSRF token in the CSRF header.
+func (cfg *apiConfig) checkCSRF(r *http.Request, sessionID uuid.UUID) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+
+	header := r.Header.Get(csrfTokenHeader)
+	return sessionID != uuid.Nil && subtle.ConstantTimeCompare([]byte(header), []byte(cfg.csrfToken(sessionID))) == 1
+}
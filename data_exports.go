@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/M-Sviridov/chirpy/internal/auth"
+	"github.com/M-Sviridov/chirpy/internal/database"
+	"github.com/M-Sviridov/chirpy/internal/export"
+	"github.com/M-Sviridov/chirpy/internal/mail"
+	"github.com/google/uuid"
+)
+
+// Statuses of a data export. An export is built in the background and
+// kept for a week once it's done, after which it's deleted.
+const (
+	dataExportPending = "pending"
+	dataExportReady   = "ready"
+	dataExportFailed  = "failed"
+)
+
+const (
+	dataExportBatch = 5
+	// dataExportLease is how long a claimed export is hidden from other
+	// workers. If the worker dies, the export is built again after it.
+	dataExportLease = 10 * time.Minute
+	// dataExportLinkExpiry is how long a download link works for.
+	dataExportLinkExpiry = 15 * time.Minute
+
+	dataExportAuditEventLimit = 10000
+)
+
+// dataExportPath returns the path an export is downloaded from.
+func dataExportPath(exportID uuid.UUID) string {
+	return "/api/me/export/" + exportID.String()
+}
+
+// dataExportDownloadURL returns a link that downloads an export without
+// any other credentials until expires.
+func (cfg *apiConfig) dataExportDownloadURL(exportID uuid.UUID, expires time.Time) string {
+	path := dataExportPath(exportID)
+	return cfg.publicURL + path + "?" + auth.SignURL(cfg.tokenHashKey, path, expires).Encode()
+}
+
+// buildDataExports builds the exports that have been asked for.
+func (cfg *apiConfig) buildDataExports(ctx context.Context) error {
+	for range dataExportBatch {
+		dataExport, err := cfg.db.ClaimPendingDataExport(ctx, int32(dataExportLease.Seconds()))
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		archive, err := cfg.buildDataExportArchive(ctx, dataExport.UserID)
+		if err != nil {
+			log.Printf("error building data export %s: %s", dataExport.ID, err)
+
+			failParams := database.FailDataExportParams{
+				ID:    dataExport.ID,
+				Error: err.Error(),
+			}
+			if err := cfg.db.FailDataExport(ctx, failParams); err != nil {
+				return err
+			}
+			continue
+		}
+
+		completeParams := database.CompleteDataExportParams{
+			ID:      dataExport.ID,
+			Archive: archive,
+		}
+		if err := cfg.db.CompleteDataExport(ctx, completeParams); err != nil {
+			return err
+		}
+
+		user, err := cfg.db.GetUserByID(ctx, dataExport.UserID)
+		if err != nil {
+			return err
+		}
+		cfg.sendMail(mail.Message{
+			To:      user.Email,
+			Subject: "Your Chirpy data export is ready",
+			Body: "The export of your Chirpy data you asked for is ready. You can download it for the next " +
+				"7 days with GET " + dataExportPath(dataExport.ID) + ".\n\n" +
+				"If you didn't ask for it, reset your password straight away.\n",
+		})
+	}
+
+	return nil
+}
+
+type dataExportSubscription struct {
+	Plan              string     `json:"plan"`
+	Status            string     `json:"status"`
+	CurrentPeriodEnd  *time.Time `json:"current_period_end"`
+	CancelAtPeriodEnd bool       `json:"cancel_at_period_end"`
+	CanceledAt        *time.Time `json:"canceled_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+type dataExportProfile struct {
+	User         userResource            `json:"user"`
+	Subscription *dataExportSubscription `json:"subscription"`
+}
+
+type dataExportChirp struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Body      string    `json:"body"`
+}
+
+type dataExportSession struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	UserAgent  string     `json:"user_agent"`
+	IPAddress  string     `json:"ip_address"`
+	ClientID   *uuid.UUID `json:"client_id"`
+	Scopes     []string   `json:"scopes"`
+}
+
+// formatExportTime formats an optional time for a CSV cell.
+func formatExportTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// buildDataExportArchive returns a ZIP archive of everything held about a
+// user. Secrets, such as password and token hashes, are left out.
+func (cfg *apiConfig) buildDataExportArchive(ctx context.Context, userID uuid.UUID) ([]byte, error) {
+	user, err := cfg.db.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("getting user: %w", err)
+	}
+
+	profile := dataExportProfile{
+		User: newUserResource(user),
+	}
+	subscription, err := cfg.db.GetSubscriptionForUser(ctx, userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("getting subscription: %w", err)
+	}
+	if err == nil {
+		profile.Subscription = &dataExportSubscription{
+			Plan:              subscription.Plan,
+			Status:            subscription.Status,
+			CancelAtPeriodEnd: subscription.CancelAtPeriodEnd,
+			CreatedAt:         subscription.CreatedAt,
+		}
+		if subscription.CurrentPeriodEnd.Valid {
+			profile.Subscription.CurrentPeriodEnd = &subscription.CurrentPeriodEnd.Time
+		}
+		if subscription.CanceledAt.Valid {
+			profile.Subscription.CanceledAt = &subscription.CanceledAt.Time
+		}
+	}
+
+	dbChirps, err := cfg.db.GetChirpsForUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("getting chirps: %w", err)
+	}
+	chirps := []dataExportChirp{}
+	chirpRows := [][]string{}
+	for _, chirp := range dbChirps {
+		chirps = append(chirps, dataExportChirp{
+			ID:        chirp.ID,
+			CreatedAt: chirp.CreatedAt,
+			UpdatedAt: chirp.UpdatedAt,
+			Body:      chirp.Body,
+		})
+		chirpRows = append(chirpRows, []string{
+			chirp.ID.String(),
+			chirp.CreatedAt.Format(time.RFC3339),
+			chirp.UpdatedAt.Format(time.RFC3339),
+			chirp.Body,
+		})
+	}
+
+	refreshTokens, err := cfg.db.GetRefreshTokensForUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("getting sessions: %w", err)
+	}
+	sessions := []dataExportSession{}
+	sessionRows := [][]string{}
+	for _, rt := range refreshTokens {
+		session := dataExportSession{
+			ID:        rt.ID,
+			CreatedAt: rt.CreatedAt,
+			ExpiresAt: rt.ExpiresAt,
+			UserAgent: rt.UserAgent,
+			IPAddress: rt.IpAddress,
+			Scopes:    rt.Scopes,
+		}
+		if rt.LastUsedAt.Valid {
+			session.LastUsedAt = &rt.LastUsedAt.Time
+		}
+		if rt.RevokedAt.Valid {
+			session.RevokedAt = &rt.RevokedAt.Time
+		}
+		clientID := ""
+		if rt.ClientID.Valid {
+			session.ClientID = &rt.ClientID.UUID
+			clientID = rt.ClientID.UUID.String()
+		}
+		sessions = append(sessions, session)
+		sessionRows = append(sessionRows, []string{
+			session.ID.String(),
+			session.CreatedAt.Format(time.RFC3339),
+			formatExportTime(session.LastUsedAt),
+			session.ExpiresAt.Format(time.RFC3339),
+			formatExportTime(session.RevokedAt),
+			session.UserAgent,
+			session.IPAddress,
+			clientID,
+		})
+	}
+
+	pats, err := cfg.db.GetPersonalAccessTokensForUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("getting personal access tokens: %w", err)
+	}
+	tokens := []personalAccessTokenResponse{}
+	for _, pat := range pats {
+		tokens = append(tokens, newPersonalAccessTokenResponse(pat))
+	}
+
+	endpoints, err := cfg.db.GetWebhookEndpointsForUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("getting webhook endpoints: %w", err)
+	}
+	webhookEndpoints := []webhookEndpointResponse{}
+	for _, endpoint := range endpoints {
+		webhookEndpoints = append(webhookEndpoints, newWebhookEndpointResponse(endpoint))
+	}
+
+	eventParams := database.GetAuditEventsForUserParams{
+		UserID: uuid.NullUUID{UUID: userID, Valid: true},
+		Limit:  dataExportAuditEventLimit,
+	}
+	events, err := cfg.db.GetAuditEventsForUser(ctx, eventParams)
+	if err != nil {
+		return nil, fmt.Errorf("getting security events: %w", err)
+	}
+
+	var buf bytes.Buffer
+	archive := export.NewArchive(&buf, time.Now().UTC())
+
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", profile},
+		{"chirps.json", chirps},
+		{"sessions.json", sessions},
+		{"personal_access_tokens.json", tokens},
+		{"webhook_endpoints.json", webhookEndpoints},
+		{"security_events.json", newAuditEventResponses(events)},
+	}
+	for _, f := range files {
+		if err := archive.AddJSON(f.name, f.data); err != nil {
+			return nil, fmt.Errorf("writing %s: %w", f.name, err)
+		}
+	}
+
+	chirpHeader := []string{"id", "created_at", "updated_at", "body"}
+	if err := archive.AddCSV("chirps.csv", chirpHeader, chirpRows); err != nil {
+		return nil, fmt.Errorf("writing chirps.csv: %w", err)
+	}
+	sessionHeader := []string{"id", "created_at", "last_used_at", "expires_at", "revoked_at", "user_agent", "ip_address", "client_id"}
+	if err := archive.AddCSV("sessions.csv", sessionHeader, sessionRows); err != nil {
+		return nil, fmt.Errorf("writing sessions.csv: %w", err)
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// purgeExpiredDataExports deletes the exports that can no longer be
+// downloaded.
+func (cfg *apiConfig) purgeExpiredDataExports(ctx context.Context) error {
+	return cfg.db.DeleteExpiredDataExports(ctx)
+}
+
+// dataExportFilename is the name an export is downloaded as.
+func dataExportFilename(dataExport database.DataExport) string {
+	return "chirpy-export-" + dataExport.CompletedAt.Time.Format("2006-01-02") + ".zip"
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"context"
+
+	"github.com/M-Sviridov/chirpy/internal/database"
+)
+
+// withTx runs fn with queries bound to a transaction, committing it if fn
+// succeeds and rolling it back otherwise.
+func (cfg *apiConfig) withTx(ctx context.Context, fn func(q *database.Queries) error) error {
+	tx, err := cfg.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(cfg.db.WithTx(tx)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+
+	"github.com/M-Sviridov/chirpy/internal/auth"
+	"github.com/M-Sviridov/chirpy/internal/database"
+	chirpymail "github.com/M-Sviridov/chirpy/internal/mail"
+	"github.com/google/uuid"
+)
+
+// Policies for what accounts whose email hasn't been verified can do.
+const (
+	unverifiedAccessFull     = "full"
+	unverifiedAccessReadOnly = "read-only"
+	unverifiedAccessNone     = "none"
+)
+
+func validateUnverifiedAccess(policy string) error {
+	switch policy {
+	case unverifiedAccessFull, unverifiedAccessReadOnly, unverifiedAccessNone:
+		return nil
+	default:
+		return fmt.Errorf("unknown unverified access policy %q", policy)
+	}
+}
+
+// validateEmail accepts a bare email address such as "user@example.com".
+func validateEmail(email string) error {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return errors.New("invalid email address")
+	}
+	return nil
+}
+
+// sendEmailVerification emails a link proving ownership of email to the
+// user. Verifying it makes email the user's address if it isn't already.
+func (cfg *apiConfig) sendEmailVerification(ctx context.Context, userID uuid.UUID, email string) error {
+	verificationToken, err := auth.MakeOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	tokenParams := database.CreateEmailVerificationTokenParams{
+		TokenHash: auth.HashToken(verificationToken, cfg.tokenHashKey),
+		UserID:    userID,
+		Email:     email,
+	}
+	if err := cfg.db.CreateEmailVerificationToken(ctx, tokenParams); err != nil {
+		return err
+	}
+
+	cfg.sendMail(chirpymail.Message{
+		To:      email,
+		Subject: "Verify your Chirpy email address",
+		Body: fmt.Sprintf("Use this link within the next 24 hours to verify your email address:\n\n"+
+			"%s/app/verify-email?token=%s\n\n"+
+			"If you didn't sign up for Chirpy or change your email, you can ignore this email.\n",
+			cfg.publicURL, verificationToken),
+	})
+
+	return nil
+}
+
+// requireWriteAccess rejects requests that change state from accounts the
+// unverified access policy restricts to reading.
+func (cfg *apiConfig) requireWriteAccess(w http.ResponseWriter, p principal) bool {
+	if p.emailVerified || cfg.unverifiedAccess == unverifiedAccessFull {
+		return true
+	}
+
+	respondWithError(w, http.StatusForbidden, "email address must be verified first")
+	return false
+}
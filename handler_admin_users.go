@@ -0,0 +1,483 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/M-Sviridov/chirpy/internal/auth"
+	"github.com/M-Sviridov/chirpy/internal/database"
+	"github.com/M-Sviridov/chirpy/internal/mail"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultAdminUserLimit = 50
+	maxAdminUserLimit     = 500
+)
+
+// adminUserResponse is a user as admins see them.
+type adminUserResponse struct {
+	userResource
+	Status               string     `json:"status"`
+	SuspendedUntil       *time.Time `json:"suspended_until"`
+	SuspensionReason     string     `json:"suspension_reason,omitempty"`
+	DeletionScheduledFor *time.Time `json:"deletion_scheduled_for"`
+}
+
+func newAdminUserResponse(user database.User) adminUserResponse {
+	rv := adminUserResponse{
+		userResource:     newUserResource(user),
+		Status:           user.Status,
+		SuspensionReason: user.SuspensionReason,
+	}
+	if user.SuspendedUntil.Valid {
+		rv.SuspendedUntil = &user.SuspendedUntil.Time
+	}
+	if user.DeletionScheduledFor.Valid {
+		rv.DeletionScheduledFor = &user.DeletionScheduledFor.Time
+	}
+	return rv
+}
+
+// escapeLike escapes the wildcards in s for use in a LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// handleAdminGetUsers lets admins search users. id matches exactly, email
+// matches any part of the address regardless of case, and status is one of
+// the user statuses. Every filter is optional.
+func (cfg *apiConfig) handleAdminGetUsers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	searchParams := database.SearchUsersParams{
+		MaxResults: defaultAdminUserLimit,
+	}
+
+	if v := query.Get("id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "couldn't parse id")
+			return
+		}
+		searchParams.ID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+	if v := query.Get("email"); v != "" {
+		searchParams.Email = sql.NullString{String: escapeLike(v), Valid: true}
+	}
+	if v := query.Get("status"); v != "" {
+		searchParams.Status = sql.NullString{String: v, Valid: true}
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxAdminUserLimit {
+			respondWithError(w, http.StatusBadRequest, "limit must be between 1 and 500")
+			return
+		}
+		searchParams.MaxResults = int32(limit)
+	}
+
+	users, err := cfg.db.SearchUsers(r.Context(), searchParams)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't search users")
+		return
+	}
+
+	rv := []adminUserResponse{}
+	for _, user := range users {
+		rv = append(rv, newAdminUserResponse(user))
+	}
+
+	respondWithJSON(w, http.StatusOK, rv)
+}
+
+// getAdminTargetUser returns the user named in the path, responding with
+// an error if there's no such user.
+func (cfg *apiConfig) getAdminTargetUser(w http.ResponseWriter, r *http.Request) (database.User, bool) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "couldn't parse user ID")
+		return database.User{}, false
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "user does not exist")
+		return user, false
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't get user")
+		return user, false
+	}
+
+	return user, true
+}
+
+// handleAdminGetUser shows admins a user along with counts of what they
+// have on the account.
+func (cfg *apiConfig) handleAdminGetUser(w http.ResponseWriter, r *http.Request) {
+	type activityVals struct {
+		Chirps               int64      `json:"chirps"`
+		ActiveSessions       int64      `json:"active_sessions"`
+		PersonalAccessTokens int64      `json:"personal_access_tokens"`
+		WebhookEndpoints     int64      `json:"webhook_endpoints"`
+		LastLoginAt          *time.Time `json:"last_login_at"`
+	}
+
+	type respVals struct {
+		adminUserResponse
+		Activity activityVals `json:"activity"`
+	}
+
+	user, ok := cfg.getAdminTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	activity, err := cfg.db.GetUserActivity(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't get user activity")
+		return
+	}
+
+	rv := respVals{
+		adminUserResponse: newAdminUserResponse(user),
+		Activity: activityVals{
+			Chirps:               activity.Chirps,
+			ActiveSessions:       activity.ActiveSessions,
+			PersonalAccessTokens: activity.PersonalAccessTokens,
+			WebhookEndpoints:     activity.WebhookEndpoints,
+		},
+	}
+	if activity.LastLoginAt.Valid {
+		rv.Activity.LastLoginAt = &activity.LastLoginAt.Time
+	}
+
+	respondWithJSON(w, http.StatusOK, rv)
+}
+
+// handleAdminSuspendUser suspends a user until a given time and signs them
+// out everywhere. Users who should be locked out for good are banned
+// instead.
+func (cfg *apiConfig) handleAdminSuspendUser(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Reason string     `json:"reason"`
+		Until  *time.Time `json:"until"`
+	}
+
+	user, ok := cfg.getAdminTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "error decoding parameters")
+		return
+	}
+
+	if user.ID == principalFromContext(r.Context()).userID {
+		respondWithError(w, http.StatusConflict, "you can't suspend your own account")
+		return
+	}
+	if params.Until == nil {
+		respondWithError(w, http.StatusBadRequest, "until is required")
+		return
+	}
+	until := params.Until.UTC()
+	if !until.After(time.Now().UTC()) {
+		respondWithError(w, http.StatusBadRequest, "until must be in the future")
+		return
+	}
+
+	suspendParams := database.SuspendUserParams{
+		ID:               user.ID,
+		SuspendedUntil:   sql.NullTime{Time: until, Valid: true},
+		SuspensionReason: params.Reason,
+	}
+
+	user, err = cfg.db.SuspendUser(r.Context(), suspendParams)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't suspend user")
+		return
+	}
+
+	if err := cfg.revokeAllCredentials(r.Context(), user.ID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't revoke sessions")
+		return
+	}
+
+	cfg.recordAuditEvent(r, adminAuditEvent(r, auditUserSuspended, user.ID, map[string]any{
+		"reason": params.Reason,
+		"until":  until,
+	}))
+
+	respondWithJSON(w, http.StatusOK, newAdminUserResponse(user))
+}
+
+// handleAdminBanUser locks a user out until an admin lifts the ban, and
+// signs them out everywhere.
+func (cfg *apiConfig) handleAdminBanUser(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Reason string `json:"reason"`
+	}
+
+	user, ok := cfg.getAdminTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "error decoding parameters")
+		return
+	}
+
+	if user.ID == principalFromContext(r.Context()).userID {
+		respondWithError(w, http.StatusConflict, "you can't ban your own account")
+		return
+	}
+
+	banParams := database.BanUserParams{
+		ID:               user.ID,
+		SuspensionReason: params.Reason,
+	}
+	user, err = cfg.db.BanUser(r.Context(), banParams)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't ban user")
+		return
+	}
+
+	if err := cfg.revokeAllCredentials(r.Context(), user.ID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't revoke sessions")
+		return
+	}
+
+	cfg.recordAuditEvent(r, adminAuditEvent(r, auditUserBanned, user.ID, map[string]any{
+		"reason": params.Reason,
+	}))
+
+	respondWithJSON(w, http.StatusOK, newAdminUserResponse(user))
+}
+
+// handleAdminUnsuspendUser lifts a user's suspension or ban.
+func (cfg *apiConfig) handleAdminUnsuspendUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.getAdminTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	if user.Status == userStatusActive {
+		respondWithError(w, http.StatusConflict, "user is not suspended or banned")
+		return
+	}
+
+	eventType := auditUserUnsuspended
+	if user.Status == userStatusBanned {
+		eventType = auditUserUnbanned
+	}
+
+	user, err := cfg.db.UnsuspendUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't unsuspend user")
+		return
+	}
+
+	cfg.recordAuditEvent(r, adminAuditEvent(r, eventType, user.ID, nil))
+
+	respondWithJSON(w, http.StatusOK, newAdminUserResponse(user))
+}
+
+// handleAdminResetUserPassword replaces a user's password with a random
+// one nobody knows, signs them out everywhere and emails them a link to
+// choose a new one, for accounts that may have been taken over.
+func (cfg *apiConfig) handleAdminResetUserPassword(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.getAdminTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	randomPassword, err := auth.MakeOpaqueToken()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't create password")
+		return
+	}
+	hash, err := auth.HashPassword(randomPassword, cfg.passwordParams)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't hash password")
+		return
+	}
+
+	passwordParams := database.UpdateUserPasswordParams{
+		ID:             user.ID,
+		HashedPassword: hash,
+	}
+	if err := cfg.db.UpdateUserPassword(r.Context(), passwordParams); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't update user details in DB")
+		return
+	}
+
+	if err := cfg.revokeAllCredentials(r.Context(), user.ID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't revoke sessions")
+		return
+	}
+
+	resetToken, err := cfg.createPasswordResetToken(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't create password reset token")
+		return
+	}
+
+	cfg.recordAuditEvent(r, adminAuditEvent(r, auditPasswordResetForced, user.ID, nil))
+
+	cfg.sendMail(mail.Message{
+		To:      user.Email,
+		Subject: "Your Chirpy password has been reset",
+		Body: "An administrator reset the password of your Chirpy account to protect it, and signed it " +
+			"out everywhere.\n\n" +
+			"Use this link within the next hour to choose a new password:\n\n" +
+			cfg.passwordResetURL(resetToken) + "\n\n" +
+			"After that, you can ask for a new link from the login page.\n",
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleAdminRevokeUserSessions signs a user out everywhere, without
+// touching their password or personal access tokens.
+func (cfg *apiConfig) handleAdminRevokeUserSessions(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.getAdminTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	if err := cfg.revokeAllSessions(r.Context(), user.ID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't revoke sessions")
+		return
+	}
+
+	cfg.recordAuditEvent(r, adminAuditEvent(r, auditSessionsRevoked, user.ID, nil))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleAdminSetChirpyRed upgrades or downgrades a user by hand, say to
+// make up for a missed payment webhook. It doesn't touch the user's
+// subscription.
+func (cfg *apiConfig) handleAdminSetChirpyRed(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		IsChirpyRed *bool `json:"is_chirpy_red"`
+	}
+
+	user, ok := cfg.getAdminTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "error decoding parameters")
+		return
+	}
+	if params.IsChirpyRed == nil {
+		respondWithError(w, http.StatusBadRequest, "is_chirpy_red is required")
+		return
+	}
+
+	eventType := auditUserUpgraded
+	if *params.IsChirpyRed {
+		err = cfg.db.UpgradeUserToChirpyRed(r.Context(), user.ID)
+	} else {
+		eventType = auditUserDowngraded
+		err = cfg.db.DowngradeUserFromChirpyRed(r.Context(), user.ID)
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't update user")
+		return
+	}
+
+	if user.IsChirpyRed != *params.IsChirpyRed {
+		cfg.recordAuditEvent(r, adminAuditEvent(r, eventType, user.ID, map[string]any{
+			"manual": true,
+		}))
+	}
+
+	user.IsChirpyRed = *params.IsChirpyRed
+	respondWithJSON(w, http.StatusOK, newAdminUserResponse(user))
+}
+
+var errLastAdmin = errors.New("can't demote the last admin")
+
+// handleAdminSetUserRole changes a user's role. A demotion takes effect on
+// the user's next request, while a promotion waits for their next access
+// token. The last admin can't be demoted, so there's always someone left
+// who can manage roles.
+func (cfg *apiConfig) handleAdminSetUserRole(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Role string `json:"role"`
+	}
+
+	user, ok := cfg.getAdminTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "error decoding parameters")
+		return
+	}
+	if err := validateRole(params.Role); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		// Locking every admin makes concurrent demotions wait for each
+		// other, so two admins can't demote one another at the same time.
+		admins, err := q.LockUsersWithRole(r.Context(), roleAdmin)
+		if err != nil {
+			return err
+		}
+		if params.Role != roleAdmin && slices.Equal(admins, []uuid.UUID{user.ID}) {
+			return errLastAdmin
+		}
+
+		return q.SetUserRole(r.Context(), database.SetUserRoleParams{
+			ID:   user.ID,
+			Role: params.Role,
+		})
+	})
+	if errors.Is(err, errLastAdmin) {
+		respondWithError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't update role")
+		return
+	}
+
+	if user.Role != params.Role {
+		cfg.recordAuditEvent(r, adminAuditEvent(r, auditRoleChanged, user.ID, map[string]any{
+			"old_role": user.Role,
+			"new_role": params.Role,
+		}))
+	}
+
+	user.Role = params.Role
+	respondWithJSON(w, http.StatusOK, newAdminUserResponse(user))
+}
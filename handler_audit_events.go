@@ -0,0 +1,135 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/M-Sviridov/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultAuditEventLimit = 100
+	maxAuditEventLimit     = 1000
+)
+
+type auditEventResponse struct {
+	ID        uuid.UUID       `json:"id"`
+	CreatedAt time.Time       `json:"created_at"`
+	UserID    *uuid.UUID      `json:"user_id"`
+	ActorID   *uuid.UUID      `json:"actor_id"`
+	EventType string          `json:"event_type"`
+	IPAddress string          `json:"ip_address"`
+	UserAgent string          `json:"user_agent"`
+	RequestID string          `json:"request_id"`
+	Details   json.RawMessage `json:"details"`
+}
+
+func newAuditEventResponses(events []database.AuditEvent) []auditEventResponse {
+	rv := []auditEventResponse{}
+	for _, event := range events {
+		resp := auditEventResponse{
+			ID:        event.ID,
+			CreatedAt: event.CreatedAt,
+			EventType: event.EventType,
+			IPAddress: event.IpAddress,
+			UserAgent: event.UserAgent,
+			RequestID: event.RequestID,
+			Details:   event.Details,
+		}
+		if event.UserID.Valid {
+			resp.UserID = &event.UserID.UUID
+		}
+		if event.ActorID.Valid {
+			resp.ActorID = &event.ActorID.UUID
+		}
+		rv = append(rv, resp)
+	}
+	return rv
+}
+
+// handleGetAuditEvents lets admins search the audit log. Every filter is
+// optional; since and until are RFC 3339 timestamps.
+func (cfg *apiConfig) handleGetAuditEvents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	eventParams := database.GetAuditEventsParams{
+		MaxResults: defaultAuditEventLimit,
+	}
+
+	for name, dst := range map[string]*uuid.NullUUID{
+		"user_id":  &eventParams.UserID,
+		"actor_id": &eventParams.ActorID,
+	} {
+		if v := query.Get(name); v != "" {
+			id, err := uuid.Parse(v)
+			if err != nil {
+				respondWithError(w, http.StatusBadRequest, "couldn't parse "+name)
+				return
+			}
+			*dst = uuid.NullUUID{UUID: id, Valid: true}
+		}
+	}
+
+	if v := query.Get("event_type"); v != "" {
+		eventParams.EventType = sql.NullString{String: v, Valid: true}
+	}
+	if v := query.Get("ip_address"); v != "" {
+		eventParams.IpAddress = sql.NullString{String: v, Valid: true}
+	}
+
+	for name, dst := range map[string]*sql.NullTime{
+		"since": &eventParams.Since,
+		"until": &eventParams.Until,
+	} {
+		if v := query.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				respondWithError(w, http.StatusBadRequest, name+" must be an RFC 3339 timestamp")
+				return
+			}
+			*dst = sql.NullTime{Time: t.UTC(), Valid: true}
+		}
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxAuditEventLimit {
+			respondWithError(w, http.StatusBadRequest, "limit must be between 1 and 1000")
+			return
+		}
+		eventParams.MaxResults = int32(limit)
+	}
+
+	events, err := cfg.db.GetAuditEvents(r.Context(), eventParams)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't get audit events")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, newAuditEventResponses(events))
+}
+
+// handleGetSecurityEvents lists the most recent audit events on the
+// caller's own account, so they can spot activity that wasn't theirs.
+func (cfg *apiConfig) handleGetSecurityEvents(w http.ResponseWriter, r *http.Request) {
+	p, ok := cfg.authenticate(w, r, "")
+	if !ok {
+		return
+	}
+
+	eventParams := database.GetAuditEventsForUserParams{
+		UserID: uuid.NullUUID{UUID: p.userID, Valid: true},
+		Limit:  defaultAuditEventLimit,
+	}
+
+	events, err := cfg.db.GetAuditEventsForUser(r.Context(), eventParams)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't get security events")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, newAuditEventResponses(events))
+}
@@ -8,7 +8,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/M-Sviridov/chirpy/internal/auth"
 	"github.com/M-Sviridov/chirpy/internal/database"
 	"github.com/google/uuid"
 )
@@ -26,6 +25,11 @@ func (cfg *apiConfig) handleCreateChirp(w http.ResponseWriter, r *http.Request)
 		UserID    uuid.UUID `json:"user_id"`
 	}
 
+	p, ok := cfg.authenticate(w, r, scopeChirpsWrite)
+	if !ok || !cfg.requireWriteAccess(w, p) {
+		return
+	}
+
 	decoder := json.NewDecoder(r.Body)
 	params := parameters{}
 	err := decoder.Decode(&params)
@@ -34,18 +38,6 @@ func (cfg *apiConfig) handleCreateChirp(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	token, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "couldn't get bearer token")
-		return
-	}
-
-	userID, err := auth.ValidateJWT(token, cfg.tokenSecret)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "JWT token is invalid")
-		return
-	}
-
 	cleanedBody, err := cfg.validateChirp(params.Body)
 	if err != nil {
 		respondWithError(w, http.StatusBadRequest, "couldn't validate chirp")
@@ -54,7 +46,7 @@ func (cfg *apiConfig) handleCreateChirp(w http.ResponseWriter, r *http.Request)
 
 	chirpParams := database.CreateChirpParams{
 		Body:   cleanedBody,
-		UserID: userID,
+		UserID: p.userID,
 	}
 
 	chirp, err := cfg.db.CreateChirp(r.Context(), chirpParams)
@@ -71,6 +63,8 @@ func (cfg *apiConfig) handleCreateChirp(w http.ResponseWriter, r *http.Request)
 		UserID:    chirp.UserID,
 	}
 
+	cfg.publishWebhookEvent(r.Context(), p.userID, webhookEventChirpCreated, rv)
+
 	respondWithJSON(w, http.StatusCreated, rv)
 }
 
@@ -124,6 +118,42 @@ func (cfg *apiConfig) handleGetChirps(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, http.StatusOK, chirps)
 }
 
+// handleGetMyChirps lists the caller's own chirps, oldest first. Tokens
+// acting on the caller's behalf need the chirps:read scope.
+func (cfg *apiConfig) handleGetMyChirps(w http.ResponseWriter, r *http.Request) {
+	type respVals struct {
+		ID        uuid.UUID `json:"id"`
+		CreatedAt time.Time `json:"created_at"`
+		UpdatedAt time.Time `json:"updated_at"`
+		Body      string    `json:"body"`
+		UserID    uuid.UUID `json:"user_id"`
+	}
+
+	p, ok := cfg.authenticate(w, r, scopeChirpsRead)
+	if !ok {
+		return
+	}
+
+	userChirps, err := cfg.db.GetChirpsForUser(r.Context(), p.userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't get chirps")
+		return
+	}
+
+	chirps := []respVals{}
+	for _, c := range userChirps {
+		chirps = append(chirps, respVals{
+			ID:        c.ID,
+			CreatedAt: c.CreatedAt,
+			UpdatedAt: c.UpdatedAt,
+			Body:      c.Body,
+			UserID:    c.UserID,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, chirps)
+}
+
 func (cfg *apiConfig) handleGetChirp(w http.ResponseWriter, r *http.Request) {
 	type respVals struct {
 		ID        uuid.UUID `json:"id"`
@@ -156,15 +186,8 @@ func (cfg *apiConfig) handleGetChirp(w http.ResponseWriter, r *http.Request) {
 }
 
 func (cfg *apiConfig) handleDeleteChirp(w http.ResponseWriter, r *http.Request) {
-	token, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "couldn't get bearer token")
-		return
-	}
-
-	userID, err := auth.ValidateJWT(token, cfg.tokenSecret)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "JWT token is invalid")
+	p, ok := cfg.authenticate(w, r, scopeChirpsWrite)
+	if !ok || !cfg.requireWriteAccess(w, p) {
 		return
 	}
 
@@ -174,7 +197,7 @@ func (cfg *apiConfig) handleDeleteChirp(w http.ResponseWriter, r *http.Request)
 		respondWithError(w, http.StatusNotFound, "couldn't get chirp")
 		return
 	}
-	if chirp.UserID != userID {
+	if chirp.UserID != p.userID {
 		respondWithError(w, http.StatusForbidden, "cannot delete anoter user's chirp")
 		return
 	}
@@ -183,6 +206,11 @@ func (cfg *apiConfig) handleDeleteChirp(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	cfg.publishWebhookEvent(r.Context(), p.userID, webhookEventChirpDeleted, map[string]any{
+		"id":      chirp.ID,
+		"user_id": chirp.UserID,
+	})
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/M-Sviridov/chirpy/internal/auth"
+	"github.com/M-Sviridov/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type dataExportResponse struct {
+	ID                   uuid.UUID  `json:"id"`
+	Status               string     `json:"status"`
+	CreatedAt            time.Time  `json:"created_at"`
+	CompletedAt          *time.Time `json:"completed_at"`
+	ExpiresAt            *time.Time `json:"expires_at"`
+	DownloadURL          string     `json:"download_url,omitempty"`
+	DownloadURLExpiresAt *time.Time `json:"download_url_expires_at,omitempty"`
+}
+
+func newDataExportResponse(dataExport database.GetDataExportForUserRow) dataExportResponse {
+	rv := dataExportResponse{
+		ID:        dataExport.ID,
+		Status:    dataExport.Status,
+		CreatedAt: dataExport.CreatedAt,
+	}
+	if dataExport.CompletedAt.Valid {
+		rv.CompletedAt = &dataExport.CompletedAt.Time
+	}
+	if dataExport.ExpiresAt.Valid {
+		rv.ExpiresAt = &dataExport.ExpiresAt.Time
+	}
+	return rv
+}
+
+// handleCreateDataExport asks for an archive of everything held about the
+// caller to be built. If one is already being built, that one is returned.
+func (cfg *apiConfig) handleCreateDataExport(w http.ResponseWriter, r *http.Request) {
+	p, ok := cfg.authenticate(w, r, "")
+	if !ok {
+		return
+	}
+	if p.delegated() {
+		respondWithError(w, http.StatusForbidden, "data exports can only be requested from a session")
+		return
+	}
+
+	dataExport, err := cfg.db.CreateDataExport(r.Context(), p.userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		pending, err := cfg.db.GetPendingDataExportForUser(r.Context(), p.userID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "couldn't get data export")
+			return
+		}
+		respondWithJSON(w, http.StatusAccepted, newDataExportResponse(database.GetDataExportForUserRow(pending)))
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't create data export")
+		return
+	}
+
+	cfg.recordAuditEvent(r, userAuditEvent(auditDataExportRequested, p.userID, map[string]any{
+		"export_id": dataExport.ID,
+	}))
+
+	respondWithJSON(w, http.StatusAccepted, newDataExportResponse(database.GetDataExportForUserRow(dataExport)))
+}
+
+// handleGetDataExport reports on one of the caller's exports. Once it's
+// ready, the response links to the archive with a short-lived signed URL.
+// Requests carrying that signature download the archive instead, without
+// other credentials, so the link works anywhere it's opened.
+func (cfg *apiConfig) handleGetDataExport(w http.ResponseWriter, r *http.Request) {
+	exportID, err := uuid.Parse(r.PathValue("exportID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "couldn't parse data export ID")
+		return
+	}
+
+	if r.URL.Query().Has("signature") {
+		cfg.downloadDataExport(w, r, exportID)
+		return
+	}
+
+	p, ok := cfg.authenticate(w, r, "")
+	if !ok {
+		return
+	}
+	if p.delegated() {
+		respondWithError(w, http.StatusForbidden, "data exports can only be downloaded from a session")
+		return
+	}
+
+	exportParams := database.GetDataExportForUserParams{
+		ID:     exportID,
+		UserID: p.userID,
+	}
+	dataExport, err := cfg.db.GetDataExportForUser(r.Context(), exportParams)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "data export does not exist")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't get data export")
+		return
+	}
+
+	rv := newDataExportResponse(dataExport)
+	if dataExport.Status == dataExportReady {
+		linkExpiresAt := time.Now().UTC().Add(dataExportLinkExpiry).Truncate(time.Second)
+		rv.DownloadURL = cfg.dataExportDownloadURL(dataExport.ID, linkExpiresAt)
+		rv.DownloadURLExpiresAt = &linkExpiresAt
+	}
+
+	respondWithJSON(w, http.StatusOK, rv)
+}
+
+// downloadDataExport serves an export's archive to a request signed with
+// dataExportDownloadURL. The link carries no other credentials, so the
+// owner's account is checked here: a link handed out before they were
+// locked out or asked for their account to be deleted stops working.
+func (cfg *apiConfig) downloadDataExport(w http.ResponseWriter, r *http.Request, exportID uuid.UUID) {
+	err := auth.VerifySignedURL(cfg.tokenHashKey, dataExportPath(exportID), r.URL.Query(), time.Now())
+	if err != nil {
+		respondWithError(w, http.StatusForbidden, "download link is invalid or has expired")
+		return
+	}
+
+	dataExport, err := cfg.db.GetReadyDataExport(r.Context(), exportID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "data export does not exist or has expired")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't get data export")
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), dataExport.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't get user")
+		return
+	}
+	if rejectLockedOutUser(w, user) {
+		return
+	}
+	if user.DeletionScheduledFor.Valid {
+		respondWithError(w, http.StatusForbidden, "account is scheduled for deletion")
+		return
+	}
+
+	cfg.recordAuditEvent(r, auditEvent{
+		eventType: auditDataExportDownloaded,
+		userID:    uuid.NullUUID{UUID: dataExport.UserID, Valid: true},
+		details: map[string]any{
+			"export_id": dataExport.ID,
+		},
+	})
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+dataExportFilename(dataExport)+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(len(dataExport.Archive)))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(dataExport.Archive)
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/M-Sviridov/chirpy/internal/auth"
+)
+
+func (cfg *apiConfig) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	type respVals struct {
+		Keys []auth.JWK `json:"keys"`
+	}
+
+	rv := respVals{
+		Keys: cfg.jwtKeys.PublicJWKs(),
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondWithJSON(w, http.StatusOK, rv)
+}
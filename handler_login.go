@@ -14,16 +14,14 @@ func (cfg *apiConfig) handleLogin(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		// UseCookies asks for a browser session kept in cookies instead
+		// of tokens in the response.
+		UseCookies bool `json:"use_cookies"`
 	}
 
-	type respVals struct {
-		ID           uuid.UUID `json:"id"`
-		CreatedAt    time.Time `json:"created_at"`
-		UpdatedAt    time.Time `json:"updated_at"`
-		Email        string    `json:"email"`
-		AccessToken  string    `json:"token"`
-		RefreshToken string    `json:"refresh_token"`
-		IsChirpyRed  bool      `json:"is_chirpy_red"`
+	type challengeVals struct {
+		TwoFactorRequired bool   `json:"two_factor_required"`
+		ChallengeToken    string `json:"challenge_token"`
 	}
 
 	decoder := json.NewDecoder(r.Body)
@@ -34,51 +32,125 @@ func (cfg *apiConfig) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	throttles := loginThrottles(r, params.Email)
+	retryAfter, err := cfg.loginRetryAfter(r.Context(), throttles)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't check login attempts")
+		return
+	}
+	if retryAfter > 0 {
+		respondWithTooManyLoginAttempts(w, retryAfter)
+		return
+	}
+
 	user, err := cfg.db.GetUserByEmail(r.Context(), params.Email)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "incorrect username or email")
+		auth.CheckPasswordHash(params.Password, cfg.dummyPasswordHash)
+		cfg.failLogin(w, r, throttles, uuid.NullUUID{})
 		return
 	}
 
 	match, err := auth.CheckPasswordHash(params.Password, user.HashedPassword)
 	if err != nil || !match {
-		respondWithError(w, http.StatusUnauthorized, "incorrect username or email")
+		cfg.failLogin(w, r, throttles, uuid.NullUUID{UUID: user.ID, Valid: true})
 		return
 	}
 
-	const expirationTimeAccessToken = time.Hour
+	cfg.rehashPasswordIfNeeded(r.Context(), user, params.Password)
 
-	accessToken, err := auth.MakeJWT(user.ID, cfg.tokenSecret, expirationTimeAccessToken)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "couldn't create JWT token")
+	if rejectLockedOutUser(w, user) {
 		return
 	}
 
-	refreshToken, err := auth.MakeRefreshToken()
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "couldn't create refresh token")
+	if !user.EmailVerifiedAt.Valid && cfg.unverifiedAccess == unverifiedAccessNone {
+		respondWithError(w, http.StatusForbidden, "email address must be verified first")
 		return
 	}
 
-	refreshTokenParams := database.CreateRefreshTokenParams{
-		Token:  refreshToken,
-		UserID: user.ID,
+	if user.TotpEnabledAt.Valid {
+		challengeToken, err := auth.MakeChallengeJWT(user.ID, cfg.jwtKeys, twoFactorChallengeExpiry)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "couldn't create challenge token")
+			return
+		}
+
+		cv := challengeVals{
+			TwoFactorRequired: true,
+			ChallengeToken:    challengeToken,
+		}
+
+		respondWithJSON(w, http.StatusOK, cv)
+		return
+	}
+
+	cfg.respondWithLogin(w, r, user, params.UseCookies)
+}
+
+// failLogin records a failed login attempt and responds to it.
+func (cfg *apiConfig) failLogin(w http.ResponseWriter, r *http.Request, throttles []loginThrottle, userID uuid.NullUUID) {
+	if err := cfg.recordLoginFailure(r, throttles, userID, "password"); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't record login attempt")
+		return
 	}
 
-	_, err = cfg.db.CreateRefreshToken(r.Context(), refreshTokenParams)
+	respondWithError(w, http.StatusUnauthorized, "incorrect username or email")
+}
+
+// respondWithLogin starts a new session for a user who passed every login
+// step and responds with its tokens, or sets them as cookies for a browser
+// session.
+func (cfg *apiConfig) respondWithLogin(w http.ResponseWriter, r *http.Request, user database.User, useCookies bool) {
+	type respVals struct {
+		ID           uuid.UUID `json:"id"`
+		CreatedAt    time.Time `json:"created_at"`
+		UpdatedAt    time.Time `json:"updated_at"`
+		Email        string    `json:"email"`
+		AccessToken  string    `json:"token,omitempty"`
+		RefreshToken string    `json:"refresh_token,omitempty"`
+		CSRFToken    string    `json:"csrf_token,omitempty"`
+		IsChirpyRed  bool      `json:"is_chirpy_red"`
+	}
+
+	// Checked again here, since every way of logging in ends here.
+	if rejectLockedOutUser(w, user) {
+		return
+	}
+
+	if err := cfg.clearLoginFailures(r.Context(), loginThrottles(r, user.Email)); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't reset login attempts")
+		return
+	}
+
+	if err := cfg.cancelAccountDeletion(r, user); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't cancel account deletion")
+		return
+	}
+
+	sess, err := cfg.createSession(r, user.ID)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "couldn't create refresh token in DB")
+		respondWithError(w, http.StatusInternalServerError, "couldn't create session")
 		return
 	}
 
+	cfg.recordAuditEvent(r, userAuditEvent(auditLoginSucceeded, user.ID, map[string]any{
+		"session_id": sess.id,
+		"two_factor": user.TotpEnabledAt.Valid,
+		"cookies":    useCookies,
+	}))
+
 	rv := respVals{
-		ID:           user.ID,
-		CreatedAt:    user.CreatedAt,
-		UpdatedAt:    user.UpdatedAt,
-		Email:        user.Email,
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		IsChirpyRed:  user.IsChirpyRed,
+		ID:          user.ID,
+		CreatedAt:   user.CreatedAt,
+		UpdatedAt:   user.UpdatedAt,
+		Email:       user.Email,
+		IsChirpyRed: user.IsChirpyRed,
+	}
+	if useCookies {
+		cfg.setSessionCookies(w, sess)
+		rv.CSRFToken = cfg.csrfToken(sess.id)
+	} else {
+		rv.AccessToken = sess.accessToken
+		rv.RefreshToken = sess.refreshToken
 	}
 
 	respondWithJSON(w, http.StatusOK, rv)
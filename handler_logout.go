@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/M-Sviridov/chirpy/internal/database"
+)
+
+func (cfg *apiConfig) handleLogout(w http.ResponseWriter, r *http.Request) {
+	p, ok := cfg.authenticate(w, r, "")
+	if !ok {
+		return
+	}
+
+	if err := cfg.revokeAccessToken(r.Context(), p); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't revoke access token")
+		return
+	}
+
+	revokeParams := database.RevokeRefreshTokenByIDParams{
+		ID:     p.sessionID,
+		UserID: p.userID,
+	}
+	if _, err := cfg.db.RevokeRefreshTokenByID(r.Context(), revokeParams); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't revoke session")
+		return
+	}
+
+	cfg.recordAuditEvent(r, userAuditEvent(auditLogout, p.userID, map[string]any{
+		"session_id": p.sessionID,
+	}))
+
+	cfg.clearSessionCookies(w)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/M-Sviridov/chirpy/internal/auth"
+	"github.com/M-Sviridov/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// handleGetAuthorization validates an authorization request for the
+// signed-in user and describes it, so the consent screen can ask whether
+// to grant it.
+func (cfg *apiConfig) handleGetAuthorization(w http.ResponseWriter, r *http.Request) {
+	type clientVals struct {
+		ID   uuid.UUID `json:"client_id"`
+		Name string    `json:"name"`
+	}
+
+	type respVals struct {
+		Client      clientVals `json:"client"`
+		Scopes      []string   `json:"scopes"`
+		RedirectURI string     `json:"redirect_uri"`
+	}
+
+	_, ok := cfg.authenticate(w, r, "")
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	params := authorizationParams{
+		ResponseType:        query.Get("response_type"),
+		ClientID:            query.Get("client_id"),
+		RedirectURI:         query.Get("redirect_uri"),
+		Scope:               query.Get("scope"),
+		State:               query.Get("state"),
+		CodeChallenge:       query.Get("code_challenge"),
+		CodeChallengeMethod: query.Get("code_challenge_method"),
+	}
+
+	req, err := cfg.validateAuthorization(r.Context(), params)
+	if err != nil {
+		respondWithAuthorizationError(w, err)
+		return
+	}
+
+	rv := respVals{
+		Client: clientVals{
+			ID:   req.client.ID,
+			Name: req.client.Name,
+		},
+		Scopes:      req.scopes,
+		RedirectURI: req.redirectURI,
+	}
+
+	respondWithJSON(w, http.StatusOK, rv)
+}
+
+// handleAuthorize records the signed-in user's decision on an
+// authorization request and returns where to send them back to the
+// client, with an authorization code if they approved.
+func (cfg *apiConfig) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		authorizationParams
+		Approve bool `json:"approve"`
+	}
+
+	type respVals struct {
+		RedirectTo string `json:"redirect_to"`
+	}
+
+	p, ok := cfg.authenticate(w, r, "")
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error decoding parameters")
+		return
+	}
+
+	req, err := cfg.validateAuthorization(r.Context(), params.authorizationParams)
+	if err != nil {
+		respondWithAuthorizationError(w, err)
+		return
+	}
+
+	if !params.Approve {
+		respondWithJSON(w, http.StatusOK, respVals{
+			RedirectTo: oauthRedirect(req.redirectURI, url.Values{
+				"error": {oauthErrorAccessDenied},
+				"state": {req.state},
+			}),
+		})
+		return
+	}
+
+	code, err := auth.MakeOpaqueToken()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't create authorization code")
+		return
+	}
+
+	codeParams := database.CreateOAuthAuthorizationCodeParams{
+		CodeHash:      auth.HashToken(code, cfg.tokenHashKey),
+		ClientID:      req.client.ID,
+		UserID:        p.userID,
+		RedirectUri:   req.redirectURI,
+		Scopes:        req.scopes,
+		CodeChallenge: req.codeChallenge,
+	}
+	if err := cfg.db.CreateOAuthAuthorizationCode(r.Context(), codeParams); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't create authorization code in DB")
+		return
+	}
+
+	cfg.recordAuditEvent(r, userAuditEvent(auditOAuthClientAuthorized, p.userID, map[string]any{
+		"client_id": req.client.ID,
+		"scopes":    req.scopes,
+	}))
+
+	respondWithJSON(w, http.StatusOK, respVals{
+		RedirectTo: oauthRedirect(req.redirectURI, url.Values{
+			"code":  {code},
+			"state": {req.state},
+		}),
+	})
+}
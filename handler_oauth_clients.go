@@ -0,0 +1,154 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/M-Sviridov/chirpy/internal/auth"
+	"github.com/M-Sviridov/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const maxClientNameLength = 100
+
+type oauthClientResponse struct {
+	ID           uuid.UUID `json:"client_id"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Confidential bool      `json:"confidential"`
+	CreatedAt    time.Time `json:"created_at"`
+	Secret       string    `json:"client_secret,omitempty"`
+}
+
+func newOAuthClientResponse(client database.OauthClient) oauthClientResponse {
+	return oauthClientResponse{
+		ID:           client.ID,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectUris,
+		Confidential: client.SecretHash.Valid,
+		CreatedAt:    client.CreatedAt,
+	}
+}
+
+func (cfg *apiConfig) handleCreateOAuthClient(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Name         string   `json:"name"`
+		RedirectURIs []string `json:"redirect_uris"`
+		// Confidential clients, such as server-side apps, get a secret.
+		// Public clients, such as mobile apps, can't keep one.
+		Confidential bool `json:"confidential"`
+	}
+
+	p, ok := cfg.authenticate(w, r, "")
+	if !ok || !cfg.requireWriteAccess(w, p) {
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error decoding parameters")
+		return
+	}
+
+	name := strings.TrimSpace(params.Name)
+	if name == "" || len(name) > maxClientNameLength {
+		respondWithError(w, http.StatusBadRequest, "client name must be between 1 and 100 characters")
+		return
+	}
+	if len(params.RedirectURIs) == 0 {
+		respondWithError(w, http.StatusBadRequest, "at least one redirect URI is required")
+		return
+	}
+	for _, uri := range params.RedirectURIs {
+		if err := validateRedirectURI(uri); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	var secret string
+	var secretHash sql.NullString
+	if params.Confidential {
+		secret, err = auth.MakeOpaqueToken()
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "couldn't create client secret")
+			return
+		}
+		secretHash = sql.NullString{String: auth.HashToken(secret, cfg.tokenHashKey), Valid: true}
+	}
+
+	clientParams := database.CreateOAuthClientParams{
+		OwnerID:      p.userID,
+		Name:         name,
+		SecretHash:   secretHash,
+		RedirectUris: params.RedirectURIs,
+	}
+
+	client, err := cfg.db.CreateOAuthClient(r.Context(), clientParams)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't create client in DB")
+		return
+	}
+
+	// The secret itself is only ever shown in this response.
+	rv := newOAuthClientResponse(client)
+	rv.Secret = secret
+
+	respondWithJSON(w, http.StatusCreated, rv)
+}
+
+func (cfg *apiConfig) handleGetOAuthClients(w http.ResponseWriter, r *http.Request) {
+	p, ok := cfg.authenticate(w, r, "")
+	if !ok {
+		return
+	}
+
+	clients, err := cfg.db.GetOAuthClientsForOwner(r.Context(), p.userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't get clients")
+		return
+	}
+
+	rv := []oauthClientResponse{}
+	for _, client := range clients {
+		rv = append(rv, newOAuthClientResponse(client))
+	}
+
+	respondWithJSON(w, http.StatusOK, rv)
+}
+
+func (cfg *apiConfig) handleDeleteOAuthClient(w http.ResponseWriter, r *http.Request) {
+	p, ok := cfg.authenticate(w, r, "")
+	if !ok {
+		return
+	}
+
+	clientID, err := uuid.Parse(r.PathValue("clientID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "couldn't parse client ID")
+		return
+	}
+
+	deleteParams := database.DeleteOAuthClientParams{
+		ID:      clientID,
+		OwnerID: p.userID,
+	}
+
+	// Deleting a client also deletes its refresh tokens and codes.
+	deleted, err := cfg.db.DeleteOAuthClient(r.Context(), deleteParams)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't delete client")
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "client does not exist")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/M-Sviridov/chirpy/internal/auth"
+	"github.com/google/uuid"
+)
+
+type oauthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope"`
+}
+
+// handleOAuthToken is the OAuth token endpoint. It exchanges authorization
+// codes for tokens and refreshes access tokens, for the client the tokens
+// were issued to only.
+func (cfg *apiConfig) handleOAuthToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		respondWithOAuthError(w, http.StatusBadRequest, oauthErrorInvalidRequest, "couldn't parse form")
+		return
+	}
+
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		cfg.exchangeAuthorizationCode(w, r)
+	case "refresh_token":
+		cfg.refreshClientAccessToken(w, r)
+	default:
+		respondWithOAuthError(w, http.StatusBadRequest, oauthErrorUnsupportedGrantType, "grant_type must be authorization_code or refresh_token")
+	}
+}
+
+func (cfg *apiConfig) exchangeAuthorizationCode(w http.ResponseWriter, r *http.Request) {
+	client, err := cfg.authenticateClient(r)
+	if err != nil {
+		respondWithOAuthError(w, http.StatusUnauthorized, oauthErrorInvalidClient, err.Error())
+		return
+	}
+
+	// The code is used up here whatever happens next, so a stolen code
+	// can't be retried against the checks below.
+	code, err := cfg.db.UseOAuthAuthorizationCode(r.Context(), auth.HashToken(r.PostForm.Get("code"), cfg.tokenHashKey))
+	if err != nil {
+		respondWithOAuthError(w, http.StatusBadRequest, oauthErrorInvalidGrant, "authorization code is invalid or has expired")
+		return
+	}
+	if code.ClientID != client.ID || code.RedirectUri != r.PostForm.Get("redirect_uri") {
+		respondWithOAuthError(w, http.StatusBadRequest, oauthErrorInvalidGrant, "authorization code was issued for another client or redirect_uri")
+		return
+	}
+	if !auth.VerifyPKCE(r.PostForm.Get("code_verifier"), code.CodeChallenge) {
+		respondWithOAuthError(w, http.StatusBadRequest, oauthErrorInvalidGrant, "code_verifier doesn't match the code_challenge")
+		return
+	}
+
+	if !cfg.checkGrantUserActive(w, r, code.UserID) {
+		return
+	}
+
+	sess, err := cfg.createClientSession(r, code.UserID, client.ID, code.Scopes)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't create session")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, oauthTokenResponse{
+		AccessToken:  sess.accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(accessTokenExpiry.Seconds()),
+		RefreshToken: sess.refreshToken,
+		Scope:        strings.Join(code.Scopes, " "),
+	})
+}
+
+func (cfg *apiConfig) refreshClientAccessToken(w http.ResponseWriter, r *http.Request) {
+	client, err := cfg.authenticateClient(r)
+	if err != nil {
+		respondWithOAuthError(w, http.StatusUnauthorized, oauthErrorInvalidClient, err.Error())
+		return
+	}
+
+	rt, err := cfg.getActiveRefreshToken(r.Context(), r.PostForm.Get("refresh_token"))
+	if err != nil || !rt.ClientID.Valid || rt.ClientID.UUID != client.ID {
+		respondWithOAuthError(w, http.StatusBadRequest, oauthErrorInvalidGrant, "refresh token is invalid or has expired")
+		return
+	}
+
+	if !cfg.checkGrantUserActive(w, r, rt.UserID) {
+		return
+	}
+
+	if err := cfg.db.TouchRefreshToken(r.Context(), rt.ID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't update refresh token in DB")
+		return
+	}
+
+	cfg.recordAuditEvent(r, userAuditEvent(auditTokenRefreshed, rt.UserID, map[string]any{
+		"session_id": rt.ID,
+		"client_id":  client.ID,
+	}))
+
+	accessToken, err := cfg.makeSessionJWT(r.Context(), rt)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't create access token")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, oauthTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(accessTokenExpiry.Seconds()),
+		Scope:       strings.Join(rt.Scopes, " "),
+	})
+}
+
+// checkGrantUserActive responds with an OAuth error and returns false if
+// the user a grant is for has been locked out of their account since it
+// was issued.
+func (cfg *apiConfig) checkGrantUserActive(w http.ResponseWriter, r *http.Request, userID uuid.UUID) bool {
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't get user")
+		return false
+	}
+
+	if lockout := accountLockout(user, time.Now().UTC()); lockout != "" {
+		respondWithOAuthError(w, http.StatusBadRequest, oauthErrorInvalidGrant, lockout)
+		return false
+	}
+	return true
+}
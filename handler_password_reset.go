@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/M-Sviridov/chirpy/internal/auth"
+	"github.com/M-Sviridov/chirpy/internal/database"
+	"github.com/M-Sviridov/chirpy/internal/mail"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Email string `json:"email"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error decoding parameters")
+		return
+	}
+
+	// The response is the same whether or not the account exists, so this
+	// endpoint can't be used to discover registered emails.
+	defer w.WriteHeader(http.StatusAccepted)
+
+	user, err := cfg.db.GetUserByEmail(r.Context(), params.Email)
+	if err != nil {
+		return
+	}
+
+	resetToken, err := cfg.createPasswordResetToken(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("error creating password reset token: %s", err)
+		return
+	}
+
+	cfg.recordAuditEvent(r, auditEvent{
+		eventType: auditPasswordResetRequest,
+		userID:    uuid.NullUUID{UUID: user.ID, Valid: true},
+	})
+
+	cfg.sendMail(mail.Message{
+		To:      user.Email,
+		Subject: "Reset your Chirpy password",
+		Body: fmt.Sprintf("Someone asked to reset the password of your Chirpy account.\n\n"+
+			"Use this link within the next hour to choose a new password:\n\n"+
+			"%s\n\n"+
+			"If it wasn't you, you can ignore this email.\n", cfg.passwordResetURL(resetToken)),
+	})
+}
+
+// createPasswordResetToken returns a new token that lets whoever holds it
+// choose a new password for a user.
+func (cfg *apiConfig) createPasswordResetToken(ctx context.Context, userID uuid.UUID) (string, error) {
+	resetToken, err := auth.MakeOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	resetParams := database.CreatePasswordResetTokenParams{
+		TokenHash: auth.HashToken(resetToken, cfg.tokenHashKey),
+		UserID:    userID,
+	}
+	if err := cfg.db.CreatePasswordResetToken(ctx, resetParams); err != nil {
+		return "", err
+	}
+	return resetToken, nil
+}
+
+// passwordResetURL returns the page a password reset token is used on.
+func (cfg *apiConfig) passwordResetURL(resetToken string) string {
+	return cfg.publicURL + "/app/reset-password?token=" + resetToken
+}
+
+// errPasswordPolicy aborts a password reset whose new password breaks the
+// password policy.
+var errPasswordPolicy = errors.New("password does not meet the password policy")
+
+func (cfg *apiConfig) handleResetPassword(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error decoding parameters")
+		return
+	}
+
+	var userID uuid.UUID
+	var violations []auth.PolicyViolation
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		userID, err = q.UsePasswordResetToken(r.Context(), auth.HashToken(params.Token, cfg.tokenHashKey))
+		if err != nil {
+			return err
+		}
+
+		// Checked here, once the account is known, so that a rejected
+		// password rolls back and leaves the token usable.
+		user, err := q.GetUserByID(r.Context(), userID)
+		if err != nil {
+			return err
+		}
+		violations = cfg.passwordPolicy.CheckPassword(params.Password, auth.EmailContextWords(user.Email)...)
+		if len(violations) > 0 {
+			return errPasswordPolicy
+		}
+
+		// Hashed only now, so a bad token or password costs no hashing.
+		hash, err := auth.HashPassword(params.Password, cfg.passwordParams)
+		if err != nil {
+			return err
+		}
+
+		passwordParams := database.UpdateUserPasswordParams{
+			ID:             userID,
+			HashedPassword: hash,
+		}
+		if err := q.UpdateUserPassword(r.Context(), passwordParams); err != nil {
+			return err
+		}
+
+		return q.ExpirePasswordResetTokens(r.Context(), userID)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusBadRequest, "reset token is invalid or has expired")
+		return
+	}
+	if errors.Is(err, errPasswordPolicy) {
+		respondWithPasswordViolations(w, violations)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't reset password")
+		return
+	}
+
+	if err := cfg.revokeAllSessions(r.Context(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't revoke sessions")
+		return
+	}
+	if err := cfg.db.RevokeAllPersonalAccessTokens(r.Context(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't revoke personal access tokens")
+		return
+	}
+
+	cfg.recordAuditEvent(r, userAuditEvent(auditPasswordReset, userID, nil))
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"net/http"
-	"time"
 
 	"github.com/M-Sviridov/chirpy/internal/auth"
 )
@@ -18,18 +17,36 @@ func (cfg *apiConfig) handleRefresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := cfg.db.GetUserFromRefreshToken(r.Context(), refreshToken)
+	rt, err := cfg.getActiveRefreshToken(r.Context(), refreshToken)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "couldn't get user from refresh token")
+		respondWithError(w, http.StatusUnauthorized, "refresh token is invalid or has expired")
 		return
 	}
 
-	if user.RevokedAt.Valid {
-		respondWithError(w, http.StatusUnauthorized, "refresh token has expired")
+	if rt.ClientID.Valid {
+		respondWithError(w, http.StatusUnauthorized, "refresh token belongs to an OAuth client")
 		return
 	}
 
-	accessToken, err := auth.MakeJWT(user.UserID, cfg.tokenSecret, time.Hour)
+	user, err := cfg.db.GetUserByID(r.Context(), rt.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't get user")
+		return
+	}
+	if rejectLockedOutUser(w, user) {
+		return
+	}
+
+	if err := cfg.db.TouchRefreshToken(r.Context(), rt.ID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't update refresh token in DB")
+		return
+	}
+
+	cfg.recordAuditEvent(r, userAuditEvent(auditTokenRefreshed, rt.UserID, map[string]any{
+		"session_id": rt.ID,
+	}))
+
+	accessToken, err := cfg.makeSessionJWT(r.Context(), rt)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "couldn't create access token")
 		return
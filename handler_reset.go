@@ -1,8 +1,14 @@
 package main
 
-import "net/http"
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+)
 
 func (cfg *apiConfig) handleReset(w http.ResponseWriter, r *http.Request) {
+	// Being an admin isn't enough: wiping every user is only for local
+	// development, so this stays off in production even for admins.
 	if cfg.platform != "dev" {
 		respondWithError(w, http.StatusForbidden, "cannot run reset on non dev platform")
 		return
@@ -15,5 +21,11 @@ func (cfg *apiConfig) handleReset(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	admin := principalFromContext(r.Context())
+	cfg.recordAuditEvent(r, auditEvent{
+		eventType: auditAdminReset,
+		actorID:   uuid.NullUUID{UUID: admin.userID, Valid: true},
+	})
+
 	respondWithJSON(w, http.StatusOK, "reset hits to 0 and delete all users in database")
 }
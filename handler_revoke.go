@@ -13,21 +13,20 @@ func (cfg *apiConfig) handleRevoke(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := cfg.db.GetUserFromRefreshToken(r.Context(), refreshToken)
+	rt, err := cfg.getActiveRefreshToken(r.Context(), refreshToken)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "couldn't get user from refresh token")
+		respondWithError(w, http.StatusUnauthorized, "refresh token is invalid or has expired")
 		return
 	}
 
-	if user.RevokedAt.Valid {
-		respondWithError(w, http.StatusUnauthorized, "refresh token has already expired")
-		return
-	}
-
-	if err := cfg.db.RevokeRefreshToken(r.Context(), refreshToken); err != nil {
+	if err := cfg.db.RevokeRefreshToken(r.Context(), rt.TokenHash); err != nil {
 		respondWithError(w, http.StatusInternalServerError, "couldn't revoke frefreshtoken")
 		return
 	}
 
+	cfg.recordAuditEvent(r, userAuditEvent(auditSessionRevoked, rt.UserID, map[string]any{
+		"session_id": rt.ID,
+	}))
+
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/M-Sviridov/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handleGetSessions(w http.ResponseWriter, r *http.Request) {
+	type respVals struct {
+		ID         uuid.UUID  `json:"id"`
+		CreatedAt  time.Time  `json:"created_at"`
+		LastUsedAt *time.Time `json:"last_used_at"`
+		ExpiresAt  time.Time  `json:"expires_at"`
+		UserAgent  string     `json:"user_agent"`
+		IPAddress  string     `json:"ip_address"`
+		ClientID   *uuid.UUID `json:"client_id"`
+		Current    bool       `json:"current"`
+	}
+
+	p, ok := cfg.authenticate(w, r, "")
+	if !ok {
+		return
+	}
+
+	refreshTokens, err := cfg.db.GetActiveRefreshTokensForUser(r.Context(), p.userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't get sessions")
+		return
+	}
+
+	sessions := []respVals{}
+	for _, rt := range refreshTokens {
+		session := respVals{
+			ID:        rt.ID,
+			CreatedAt: rt.CreatedAt,
+			ExpiresAt: rt.ExpiresAt,
+			UserAgent: rt.UserAgent,
+			IPAddress: rt.IpAddress,
+			Current:   rt.ID == p.sessionID,
+		}
+		if rt.LastUsedAt.Valid {
+			session.LastUsedAt = &rt.LastUsedAt.Time
+		}
+		if rt.ClientID.Valid {
+			session.ClientID = &rt.ClientID.UUID
+		}
+		sessions = append(sessions, session)
+	}
+
+	respondWithJSON(w, http.StatusOK, sessions)
+}
+
+func (cfg *apiConfig) handleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	p, ok := cfg.authenticate(w, r, "")
+	if !ok {
+		return
+	}
+
+	sessionID, err := uuid.Parse(r.PathValue("sessionID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "couldn't parse session ID")
+		return
+	}
+
+	revokeParams := database.RevokeRefreshTokenByIDParams{
+		ID:     sessionID,
+		UserID: p.userID,
+	}
+
+	revoked, err := cfg.db.RevokeRefreshTokenByID(r.Context(), revokeParams)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't revoke session")
+		return
+	}
+	if revoked == 0 {
+		respondWithError(w, http.StatusNotFound, "session does not exist")
+		return
+	}
+
+	cfg.recordAuditEvent(r, userAuditEvent(auditSessionRevoked, p.userID, map[string]any{
+		"session_id": sessionID,
+	}))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handleRevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	p, ok := cfg.authenticate(w, r, "")
+	if !ok {
+		return
+	}
+
+	revokeParams := database.RevokeOtherRefreshTokensParams{
+		UserID: p.userID,
+		ID:     p.sessionID,
+	}
+
+	if err := cfg.db.RevokeOtherRefreshTokens(r.Context(), revokeParams); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't revoke sessions")
+		return
+	}
+
+	cfg.recordAuditEvent(r, userAuditEvent(auditSessionsRevoked, p.userID, map[string]any{
+		"kept_session_id": p.sessionID,
+	}))
+
+	w.WriteHeader(http.StatusNoContent)
+}
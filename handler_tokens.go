@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/M-Sviridov/chirpy/internal/auth"
+	"github.com/M-Sviridov/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultTokenExpiryDays = 30
+	maxTokenExpiryDays     = 365
+	maxTokenNameLength     = 100
+)
+
+type personalAccessTokenResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	Token      string     `json:"token,omitempty"`
+}
+
+func newPersonalAccessTokenResponse(pat database.PersonalAccessToken) personalAccessTokenResponse {
+	rv := personalAccessTokenResponse{
+		ID:        pat.ID,
+		Name:      pat.Name,
+		Scopes:    pat.Scopes,
+		CreatedAt: pat.CreatedAt,
+		ExpiresAt: pat.ExpiresAt,
+	}
+	if pat.LastUsedAt.Valid {
+		rv.LastUsedAt = &pat.LastUsedAt.Time
+	}
+	return rv
+}
+
+func (cfg *apiConfig) handleCreateToken(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Name          string   `json:"name"`
+		Scopes        []string `json:"scopes"`
+		ExpiresInDays int32    `json:"expires_in_days"`
+	}
+
+	p, ok := cfg.authenticate(w, r, "")
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error decoding parameters")
+		return
+	}
+
+	name := strings.TrimSpace(params.Name)
+	if name == "" || len(name) > maxTokenNameLength {
+		respondWithError(w, http.StatusBadRequest, "token name must be between 1 and 100 characters")
+		return
+	}
+	scopes, err := validateScopes(params.Scopes)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if params.ExpiresInDays == 0 {
+		params.ExpiresInDays = defaultTokenExpiryDays
+	}
+	if params.ExpiresInDays < 1 || params.ExpiresInDays > maxTokenExpiryDays {
+		respondWithError(w, http.StatusBadRequest, "expires_in_days must be between 1 and 365")
+		return
+	}
+
+	token, err := auth.MakePersonalAccessToken()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't create personal access token")
+		return
+	}
+
+	tokenParams := database.CreatePersonalAccessTokenParams{
+		UserID:        p.userID,
+		Name:          name,
+		TokenHash:     auth.HashToken(token, cfg.tokenHashKey),
+		Scopes:        scopes,
+		ExpiresInDays: params.ExpiresInDays,
+	}
+
+	pat, err := cfg.db.CreatePersonalAccessToken(r.Context(), tokenParams)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't create personal access token in DB")
+		return
+	}
+
+	cfg.recordAuditEvent(r, userAuditEvent(auditTokenCreated, p.userID, map[string]any{
+		"token_id": pat.ID,
+		"name":     pat.Name,
+		"scopes":   pat.Scopes,
+	}))
+
+	// The token itself is only ever shown in this response.
+	rv := newPersonalAccessTokenResponse(pat)
+	rv.Token = token
+
+	respondWithJSON(w, http.StatusCreated, rv)
+}
+
+func (cfg *apiConfig) handleGetTokens(w http.ResponseWriter, r *http.Request) {
+	p, ok := cfg.authenticate(w, r, "")
+	if !ok {
+		return
+	}
+
+	pats, err := cfg.db.GetPersonalAccessTokensForUser(r.Context(), p.userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't get personal access tokens")
+		return
+	}
+
+	tokens := []personalAccessTokenResponse{}
+	for _, pat := range pats {
+		tokens = append(tokens, newPersonalAccessTokenResponse(pat))
+	}
+
+	respondWithJSON(w, http.StatusOK, tokens)
+}
+
+func (cfg *apiConfig) handleRevokeToken(w http.ResponseWriter, r *http.Request) {
+	p, ok := cfg.authenticate(w, r, "")
+	if !ok {
+		return
+	}
+
+	tokenID, err := uuid.Parse(r.PathValue("tokenID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "couldn't parse token ID")
+		return
+	}
+
+	revokeParams := database.RevokePersonalAccessTokenParams{
+		ID:     tokenID,
+		UserID: p.userID,
+	}
+
+	revoked, err := cfg.db.RevokePersonalAccessToken(r.Context(), revokeParams)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't revoke personal access token")
+		return
+	}
+	if revoked == 0 {
+		respondWithError(w, http.StatusNotFound, "personal access token does not exist")
+		return
+	}
+
+	cfg.recordAuditEvent(r, userAuditEvent(auditTokenRevoked, p.userID, map[string]any{
+		"token_id": tokenID,
+	}))
+
+	w.WriteHeader(http.StatusNoContent)
+}
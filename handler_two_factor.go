@@ -0,0 +1,283 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/M-Sviridov/chirpy/internal/auth"
+	"github.com/M-Sviridov/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handleEnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	type respVals struct {
+		Secret          string   `json:"secret"`
+		ProvisioningURI string   `json:"provisioning_uri"`
+		RecoveryCodes   []string `json:"recovery_codes"`
+	}
+
+	p, ok := cfg.authenticate(w, r, "")
+	if !ok {
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), p.userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "couldn't get user")
+		return
+	}
+	if user.TotpEnabledAt.Valid {
+		respondWithError(w, http.StatusConflict, "two-factor authentication is already enabled")
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't generate TOTP secret")
+		return
+	}
+
+	recoveryCodes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't generate recovery codes")
+		return
+	}
+
+	encryptedSecret, err := auth.EncryptSecret(cfg.totpKey, secret)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't encrypt TOTP secret")
+		return
+	}
+
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		secretParams := database.SetUserTOTPSecretParams{
+			TotpSecret: encryptedSecret,
+			ID:         user.ID,
+		}
+		if err := q.SetUserTOTPSecret(r.Context(), secretParams); err != nil {
+			return err
+		}
+
+		if err := q.DeleteRecoveryCodes(r.Context(), user.ID); err != nil {
+			return err
+		}
+
+		for _, code := range recoveryCodes {
+			codeParams := database.CreateRecoveryCodeParams{
+				UserID:   user.ID,
+				CodeHash: auth.HashToken(code, cfg.tokenHashKey),
+			}
+			if err := q.CreateRecoveryCode(r.Context(), codeParams); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't save two-factor enrollment")
+		return
+	}
+
+	rv := respVals{
+		Secret:          secret,
+		ProvisioningURI: auth.TOTPProvisioningURI(secret, user.Email, twoFactorIssuer),
+		RecoveryCodes:   recoveryCodes,
+	}
+
+	respondWithJSON(w, http.StatusOK, rv)
+}
+
+func (cfg *apiConfig) handleConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Code string `json:"code"`
+	}
+
+	p, ok := cfg.authenticate(w, r, "")
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error decoding parameters")
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), p.userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "couldn't get user")
+		return
+	}
+	if user.TotpEnabledAt.Valid {
+		respondWithError(w, http.StatusConflict, "two-factor authentication is already enabled")
+		return
+	}
+	if !user.TotpSecret.Valid {
+		respondWithError(w, http.StatusBadRequest, "two-factor enrollment has not been started")
+		return
+	}
+
+	secret, err := auth.DecryptSecret(cfg.totpKey, user.TotpSecret.String)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't decrypt TOTP secret")
+		return
+	}
+
+	step, ok := auth.ValidateTOTP(secret, params.Code, time.Now())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "invalid two-factor code")
+		return
+	}
+
+	enableParams := database.EnableUserTOTPParams{
+		ID:           user.ID,
+		TotpLastStep: step,
+	}
+	if err := cfg.db.EnableUserTOTP(r.Context(), enableParams); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't enable two-factor authentication")
+		return
+	}
+
+	cfg.recordAuditEvent(r, userAuditEvent(auditTwoFactorEnabled, user.ID, nil))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handleDisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+
+	p, ok := cfg.authenticate(w, r, "")
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error decoding parameters")
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), p.userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "couldn't get user")
+		return
+	}
+	if !user.TotpEnabledAt.Valid {
+		respondWithError(w, http.StatusBadRequest, "two-factor authentication is not enabled")
+		return
+	}
+
+	match, err := auth.CheckPasswordHash(params.Password, user.HashedPassword)
+	if err != nil || !match {
+		respondWithError(w, http.StatusUnauthorized, "incorrect password")
+		return
+	}
+
+	verified, err := cfg.verifySecondFactor(r.Context(), user, params.Code)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't verify two-factor code")
+		return
+	}
+	if !verified {
+		respondWithError(w, http.StatusUnauthorized, "invalid two-factor code")
+		return
+	}
+
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		if err := q.DisableUserTOTP(r.Context(), user.ID); err != nil {
+			return err
+		}
+		return q.DeleteRecoveryCodes(r.Context(), user.ID)
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't disable two-factor authentication")
+		return
+	}
+
+	cfg.recordAuditEvent(r, userAuditEvent(auditTwoFactorDisabled, user.ID, nil))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleLoginTwoFactor completes a login started by handleLogin. Each
+// challenge token allows a single attempt, so guessing codes costs a full
+// password login per guess.
+func (cfg *apiConfig) handleLoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"`
+		UseCookies     bool   `json:"use_cookies"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error decoding parameters")
+		return
+	}
+
+	claims, err := auth.ParseChallengeJWT(params.ChallengeToken, cfg.jwtKeys)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "challenge token is invalid")
+		return
+	}
+
+	userID := uuid.MustParse(claims.Subject)
+	revokeParams := database.RevokeAccessTokenParams{
+		Jti:       claims.ID,
+		UserID:    userID,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}
+	consumed, err := cfg.db.RevokeAccessToken(r.Context(), revokeParams)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't consume challenge token")
+		return
+	}
+	if consumed == 0 {
+		respondWithError(w, http.StatusUnauthorized, "challenge token has already been used")
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "couldn't get user from challenge token")
+		return
+	}
+
+	throttles := loginThrottles(r, user.Email)
+	retryAfter, err := cfg.loginRetryAfter(r.Context(), throttles)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't check login attempts")
+		return
+	}
+	if retryAfter > 0 {
+		respondWithTooManyLoginAttempts(w, retryAfter)
+		return
+	}
+
+	verified, err := cfg.verifySecondFactor(r.Context(), user, params.Code)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't verify two-factor code")
+		return
+	}
+	if !verified {
+		if err := cfg.recordLoginFailure(r, throttles, uuid.NullUUID{UUID: user.ID, Valid: true}, "two_factor"); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "couldn't record login attempt")
+			return
+		}
+		respondWithError(w, http.StatusUnauthorized, "invalid two-factor code")
+		return
+	}
+
+	cfg.respondWithLogin(w, r, user, params.UseCookies)
+}
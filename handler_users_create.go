@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
 	"time"
 
@@ -17,11 +18,12 @@ func (cfg *apiConfig) handleCreateUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	type respVals struct {
-		ID          uuid.UUID `json:"id"`
-		CreatedAt   time.Time `json:"created_at"`
-		UpdatedAt   time.Time `json:"updated_at"`
-		Email       string    `json:"email"`
-		IsChirpyRed bool      `json:"is_chirpy_red"`
+		ID            uuid.UUID `json:"id"`
+		CreatedAt     time.Time `json:"created_at"`
+		UpdatedAt     time.Time `json:"updated_at"`
+		Email         string    `json:"email"`
+		EmailVerified bool      `json:"email_verified"`
+		IsChirpyRed   bool      `json:"is_chirpy_red"`
 	}
 
 	decoder := json.NewDecoder(r.Body)
@@ -32,7 +34,15 @@ func (cfg *apiConfig) handleCreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hash, err := auth.HashPassword(params.Password)
+	if err := validateEmail(params.Email); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid email address")
+		return
+	}
+	if !cfg.checkPassword(w, params.Password, params.Email) {
+		return
+	}
+
+	hash, err := auth.HashPassword(params.Password, cfg.passwordParams)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "couldn't hash password")
 		return
@@ -49,12 +59,17 @@ func (cfg *apiConfig) handleCreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := cfg.sendEmailVerification(r.Context(), user.ID, user.Email); err != nil {
+		log.Printf("error sending email verification: %s", err)
+	}
+
 	rv := respVals{
-		ID:          user.ID,
-		CreatedAt:   user.CreatedAt,
-		UpdatedAt:   user.UpdatedAt,
-		Email:       user.Email,
-		IsChirpyRed: user.IsChirpyRed,
+		ID:            user.ID,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt.Valid,
+		IsChirpyRed:   user.IsChirpyRed,
 	}
 
 	respondWithJSON(w, http.StatusCreated, rv)
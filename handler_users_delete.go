@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/M-Sviridov/chirpy/internal/database"
+	"github.com/M-Sviridov/chirpy/internal/mail"
+)
+
+// handleDeleteUser schedules the caller's account for deletion once they
+// confirm their password, and signs them out everywhere.
+func (cfg *apiConfig) handleDeleteUser(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Password string `json:"password"`
+	}
+
+	type respVals struct {
+		DeletionScheduledFor time.Time `json:"deletion_scheduled_for"`
+	}
+
+	p, ok := cfg.authenticate(w, r, "")
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error decoding parameters")
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), p.userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "couldn't get user")
+		return
+	}
+
+	if !cfg.reauthenticate(w, r, p, user, params.Password) {
+		return
+	}
+
+	deletionParams := database.ScheduleUserDeletionParams{
+		GracePeriodDays: cfg.accountDeletionGraceDays,
+		ID:              user.ID,
+	}
+	deletionScheduledFor, err := cfg.db.ScheduleUserDeletion(r.Context(), deletionParams)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't schedule account deletion")
+		return
+	}
+
+	if err := cfg.revokeAllCredentials(r.Context(), user.ID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't revoke sessions")
+		return
+	}
+	cfg.clearSessionCookies(w)
+
+	cfg.recordAuditEvent(r, userAuditEvent(auditAccountDeletionScheduled, user.ID, map[string]any{
+		"deletion_scheduled_for": deletionScheduledFor,
+	}))
+
+	cfg.sendMail(mail.Message{
+		To:      user.Email,
+		Subject: "Your Chirpy account will be deleted",
+		Body: "Your Chirpy account is scheduled for deletion on " +
+			deletionScheduledFor.Format("2 January 2006") + ".\n\n" +
+			"Until then, you can keep your account by logging back in. If you didn't ask " +
+			"for this, log in and reset your password straight away.\n",
+	})
+
+	respondWithJSON(w, http.StatusAccepted, respVals{
+		DeletionScheduledFor: deletionScheduledFor,
+	})
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/M-Sviridov/chirpy/internal/auth"
+	"github.com/M-Sviridov/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// userResource is the full representation of a user, as returned to the
+// user themselves.
+type userResource struct {
+	ID               uuid.UUID `json:"id"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+	Email            string    `json:"email"`
+	PendingEmail     string    `json:"pending_email,omitempty"`
+	EmailVerified    bool      `json:"email_verified"`
+	IsChirpyRed      bool      `json:"is_chirpy_red"`
+	Role             string    `json:"role"`
+	TwoFactorEnabled bool      `json:"two_factor_enabled"`
+}
+
+func newUserResource(user database.User) userResource {
+	return userResource{
+		ID:               user.ID,
+		CreatedAt:        user.CreatedAt,
+		UpdatedAt:        user.UpdatedAt,
+		Email:            user.Email,
+		PendingEmail:     user.PendingEmail.String,
+		EmailVerified:    user.EmailVerifiedAt.Valid,
+		IsChirpyRed:      user.IsChirpyRed,
+		Role:             user.Role,
+		TwoFactorEnabled: user.TotpEnabledAt.Valid,
+	}
+}
+
+// handlePatchUser applies a JSON Merge Patch (RFC 7396) to the caller's
+// user. Only the members present are changed. Changing the password or the
+// email address can only be done from a session, and also needs the
+// current password, in current_password.
+func (cfg *apiConfig) handlePatchUser(w http.ResponseWriter, r *http.Request) {
+	p, ok := cfg.authenticate(w, r, scopeProfileWrite)
+	if !ok {
+		return
+	}
+
+	var patch map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil || patch == nil {
+		respondWithError(w, http.StatusBadRequest, "request body must be a JSON object")
+		return
+	}
+
+	var email, password, currentPassword *string
+	for member, value := range patch {
+		var dst **string
+		switch member {
+		case "email":
+			dst = &email
+		case "password":
+			dst = &password
+		case "current_password":
+			dst = &currentPassword
+		default:
+			respondWithError(w, http.StatusBadRequest, member+" cannot be changed")
+			return
+		}
+
+		// These members can't be removed, so null isn't allowed either.
+		var s string
+		if bytes.Equal(value, []byte("null")) || json.Unmarshal(value, &s) != nil {
+			respondWithError(w, http.StatusBadRequest, member+" must be a string")
+			return
+		}
+		*dst = &s
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), p.userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "couldn't get user")
+		return
+	}
+
+	emailChanged := email != nil && *email != user.Email
+	if emailChanged || password != nil {
+		// Whoever holds a stolen token could otherwise take the account
+		// over by pointing its email address somewhere else and resetting
+		// the password.
+		var current string
+		if currentPassword != nil {
+			current = *currentPassword
+		}
+		if !cfg.reauthenticate(w, r, p, user, current) {
+			return
+		}
+	}
+
+	if emailChanged {
+		if err := validateEmail(*email); err != nil {
+			respondWithError(w, http.StatusBadRequest, "invalid email address")
+			return
+		}
+		if _, err := cfg.db.GetUserByEmail(r.Context(), *email); err == nil {
+			respondWithError(w, http.StatusConflict, "email address is already in use")
+			return
+		}
+	}
+
+	var newHash string
+	if password != nil {
+		policyEmail := user.Email
+		if emailChanged {
+			policyEmail = *email
+		}
+		if !cfg.checkPassword(w, *password, policyEmail) {
+			return
+		}
+
+		newHash, err = auth.HashPassword(*password, cfg.passwordParams)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "couldn't hash password")
+			return
+		}
+	}
+
+	// Every change is made in one transaction, so the patch is applied
+	// completely or not at all.
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		if password != nil {
+			passwordParams := database.UpdateUserPasswordParams{
+				ID:             p.userID,
+				HashedPassword: newHash,
+			}
+			if err := q.UpdateUserPassword(r.Context(), passwordParams); err != nil {
+				return err
+			}
+			if err := signOutOtherSessions(r.Context(), q, p); err != nil {
+				return err
+			}
+		}
+		if emailChanged {
+			return setPendingEmail(r.Context(), q, user.ID, *email)
+		}
+		return nil
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't update user details in DB")
+		return
+	}
+
+	if password != nil {
+		cfg.recordAuditEvent(r, userAuditEvent(auditPasswordChanged, p.userID, nil))
+	}
+	if emailChanged {
+		cfg.announceEmailChange(r, user, *email)
+	}
+
+	user, err = cfg.db.GetUserByID(r.Context(), p.userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't get user")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, newUserResource(user))
+}
@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"log"
 	"net/http"
 
 	"github.com/M-Sviridov/chirpy/internal/auth"
 	"github.com/M-Sviridov/chirpy/internal/database"
+	"github.com/M-Sviridov/chirpy/internal/mail"
+	"github.com/google/uuid"
 )
 
 func (cfg *apiConfig) handleUpdateUser(w http.ResponseWriter, r *http.Request) {
@@ -15,48 +19,141 @@ func (cfg *apiConfig) handleUpdateUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	type respVals struct {
-		Email string `json:"email"`
+		Email        string `json:"email"`
+		PendingEmail string `json:"pending_email,omitempty"`
 	}
 
-	accessToken, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "couldn't get bearer token")
+	p, ok := cfg.authenticate(w, r, scopeProfileWrite)
+	if !ok {
 		return
 	}
 
-	userID, err := auth.ValidateJWT(accessToken, cfg.tokenSecret)
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "JWT token is invalid")
+		respondWithError(w, http.StatusInternalServerError, "error decoding parameters")
 		return
 	}
 
-	decoder := json.NewDecoder(r.Body)
-	params := parameters{}
-	err = decoder.Decode(&params)
+	user, err := cfg.db.GetUserByID(r.Context(), p.userID)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "error decoding parameters")
+		respondWithError(w, http.StatusNotFound, "couldn't get user")
 		return
 	}
 
-	hash, err := auth.HashPassword(params.Password)
+	emailChanged := params.Email != user.Email
+	if emailChanged && p.delegated() {
+		respondWithError(w, http.StatusForbidden, "email address can only be changed from a session")
+		return
+	}
+	if emailChanged {
+		if err := validateEmail(params.Email); err != nil {
+			respondWithError(w, http.StatusBadRequest, "invalid email address")
+			return
+		}
+		if _, err := cfg.db.GetUserByEmail(r.Context(), params.Email); err == nil {
+			respondWithError(w, http.StatusConflict, "email address is already in use")
+			return
+		}
+	}
+
+	samePassword, _ := auth.CheckPasswordHash(params.Password, user.HashedPassword)
+	if !samePassword && p.delegated() {
+		respondWithError(w, http.StatusForbidden, "password can only be changed from a session")
+		return
+	}
+	if !samePassword && !cfg.checkPassword(w, params.Password, params.Email) {
+		return
+	}
+
+	hash, err := auth.HashPassword(params.Password, cfg.passwordParams)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "couldn't hash password")
 		return
 	}
 
-	userParams := database.UpdateUserParams{
-		Email:          params.Email,
-		HashedPassword: hash,
-		ID:             userID,
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		passwordParams := database.UpdateUserPasswordParams{
+			ID:             p.userID,
+			HashedPassword: hash,
+		}
+		if err := q.UpdateUserPassword(r.Context(), passwordParams); err != nil {
+			return err
+		}
+		if emailChanged {
+			if err := setPendingEmail(r.Context(), q, user.ID, params.Email); err != nil {
+				return err
+			}
+		}
+		if !samePassword {
+			return signOutOtherSessions(r.Context(), q, p)
+		}
+		return nil
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't update user details in DB")
+		return
 	}
 
 	rv := respVals{
-		Email: userParams.Email,
+		Email: user.Email,
 	}
 
-	if err := cfg.db.UpdateUser(r.Context(), userParams); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "couldn't update user details in DB")
+	if !samePassword {
+		cfg.recordAuditEvent(r, userAuditEvent(auditPasswordChanged, p.userID, nil))
+	}
+	if emailChanged {
+		cfg.announceEmailChange(r, user, params.Email)
+		rv.PendingEmail = params.Email
 	}
 
 	respondWithJSON(w, http.StatusOK, rv)
 }
+
+// signOutOtherSessions ends every session of the caller's but the current
+// one after their password changed, and revokes their personal access
+// tokens, so whoever knew the old password is locked out along with
+// anything they made with it. Only sessions can change the password, so
+// the caller's own credentials survive.
+func signOutOtherSessions(ctx context.Context, q *database.Queries, p principal) error {
+	revokeParams := database.RevokeOtherRefreshTokensParams{
+		UserID: p.userID,
+		ID:     p.sessionID,
+	}
+	if err := q.RevokeOtherRefreshTokens(ctx, revokeParams); err != nil {
+		return err
+	}
+	if err := revokeUserAccessTokens(ctx, q, p.userID); err != nil {
+		return err
+	}
+	return q.RevokeAllPersonalAccessTokens(ctx, p.userID)
+}
+
+// setPendingEmail records newEmail as the address the user is changing
+// to. The current address stays in use until the new one is verified.
+func setPendingEmail(ctx context.Context, q *database.Queries, userID uuid.UUID, newEmail string) error {
+	return q.SetUserPendingEmail(ctx, database.SetUserPendingEmailParams{
+		PendingEmail: newEmail,
+		ID:           userID,
+	})
+}
+
+// announceEmailChange asks for the new address set by setPendingEmail to
+// be verified, and tells the current address about the change in case the
+// account was taken over.
+func (cfg *apiConfig) announceEmailChange(r *http.Request, user database.User, newEmail string) {
+	cfg.recordAuditEvent(r, userAuditEvent(auditEmailChangeRequested, user.ID, nil))
+
+	if err := cfg.sendEmailVerification(r.Context(), user.ID, newEmail); err != nil {
+		log.Printf("error sending email verification: %s", err)
+	}
+
+	cfg.sendMail(mail.Message{
+		To:      user.Email,
+		Subject: "Your Chirpy email address is changing",
+		Body: "Someone asked to change the email address of your Chirpy account.\n\n" +
+			"The change takes effect once the new address is verified. If it wasn't you, " +
+			"reset your password straight away.\n",
+	})
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/M-Sviridov/chirpy/internal/auth"
+	"github.com/M-Sviridov/chirpy/internal/database"
+	"github.com/lib/pq"
+)
+
+func (cfg *apiConfig) handleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Token string `json:"token"`
+	}
+
+	type respVals struct {
+		Email string `json:"email"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error decoding parameters")
+		return
+	}
+
+	var user database.User
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		verification, err := q.UseEmailVerificationToken(r.Context(), auth.HashToken(params.Token, cfg.tokenHashKey))
+		if err != nil {
+			return err
+		}
+
+		verifyParams := database.VerifyUserEmailParams{
+			Email: verification.Email,
+			ID:    verification.UserID,
+		}
+		user, err = q.VerifyUserEmail(r.Context(), verifyParams)
+		return err
+	})
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+		respondWithError(w, http.StatusConflict, "email address is already in use")
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusBadRequest, "verification token is invalid or has expired")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't verify email address")
+		return
+	}
+
+	cfg.recordAuditEvent(r, userAuditEvent(auditEmailVerified, user.ID, nil))
+
+	rv := respVals{
+		Email: user.Email,
+	}
+
+	respondWithJSON(w, http.StatusOK, rv)
+}
+
+func (cfg *apiConfig) handleResendEmailVerification(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Email string `json:"email"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error decoding parameters")
+		return
+	}
+
+	// As with password resets, the response doesn't reveal whether the
+	// account exists.
+	defer w.WriteHeader(http.StatusAccepted)
+
+	user, err := cfg.db.GetUserByEmail(r.Context(), params.Email)
+	if err != nil {
+		return
+	}
+
+	email := user.Email
+	if user.PendingEmail.Valid {
+		email = user.PendingEmail.String
+	} else if user.EmailVerifiedAt.Valid {
+		return
+	}
+
+	// Going over the limit is silent too, for the same reason.
+	throttle := loginThrottle{
+		key:  "verification_mail:" + strings.ToLower(email),
+		rule: verificationMailThrottle,
+	}
+	allowed, err := cfg.throttleAttempt(r.Context(), throttle)
+	if err != nil {
+		log.Printf("error throttling email verification: %s", err)
+		return
+	}
+	if !allowed {
+		return
+	}
+
+	if err := cfg.sendEmailVerification(r.Context(), user.ID, email); err != nil {
+		log.Printf("error sending email verification: %s", err)
+	}
+}
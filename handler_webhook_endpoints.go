@@ -0,0 +1,301 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/M-Sviridov/chirpy/internal/auth"
+	"github.com/M-Sviridov/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const webhookDeliveryLogLimit = 100
+
+type webhookEndpointResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	URL       string     `json:"url"`
+	Events    []string   `json:"events"`
+	ClientID  *uuid.UUID `json:"client_id"`
+	CreatedAt time.Time  `json:"created_at"`
+	Secret    string     `json:"secret,omitempty"`
+}
+
+func newWebhookEndpointResponse(endpoint database.WebhookEndpoint) webhookEndpointResponse {
+	rv := webhookEndpointResponse{
+		ID:        endpoint.ID,
+		URL:       endpoint.Url,
+		Events:    endpoint.EventTypes,
+		CreatedAt: endpoint.CreatedAt,
+	}
+	if endpoint.ClientID.Valid {
+		rv.ClientID = &endpoint.ClientID.UUID
+	}
+	return rv
+}
+
+type webhookDeliveryResponse struct {
+	ID             uuid.UUID       `json:"id"`
+	EventID        uuid.UUID       `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int32           `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at"`
+	LastStatusCode *int32          `json:"last_status_code"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+}
+
+func newWebhookDeliveryResponse(delivery database.WebhookDelivery) webhookDeliveryResponse {
+	rv := webhookDeliveryResponse{
+		ID:        delivery.ID,
+		EventID:   delivery.EventID,
+		EventType: delivery.EventType,
+		Payload:   delivery.Payload,
+		Status:    delivery.Status,
+		Attempts:  delivery.Attempts,
+		LastError: delivery.LastError,
+		CreatedAt: delivery.CreatedAt,
+	}
+	if delivery.Status == "pending" {
+		rv.NextAttemptAt = &delivery.NextAttemptAt
+	}
+	if delivery.LastAttemptAt.Valid {
+		rv.LastAttemptAt = &delivery.LastAttemptAt.Time
+	}
+	if delivery.LastStatusCode.Valid {
+		rv.LastStatusCode = &delivery.LastStatusCode.Int32
+	}
+	if delivery.DeliveredAt.Valid {
+		rv.DeliveredAt = &delivery.DeliveredAt.Time
+	}
+	return rv
+}
+
+// validateWebhookEvents checks that events is a non-empty list of known
+// event types and returns it sorted without duplicates.
+func validateWebhookEvents(events []string) ([]string, error) {
+	if len(events) == 0 {
+		return nil, errors.New("at least one event is required")
+	}
+
+	for _, event := range events {
+		if !slices.Contains(webhookEventTypes, event) {
+			return nil, fmt.Errorf("unknown event %q", event)
+		}
+	}
+
+	events = slices.Clone(events)
+	slices.Sort(events)
+	return slices.Compact(events), nil
+}
+
+func (cfg *apiConfig) handleCreateWebhookEndpoint(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+	}
+
+	p, ok := cfg.authenticate(w, r, scopeWebhooks)
+	if !ok || !cfg.requireWriteAccess(w, p) {
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error decoding parameters")
+		return
+	}
+
+	if err := cfg.validateWebhookURL(params.URL); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	events, err := validateWebhookEvents(params.Events)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	secret, err := auth.MakeOpaqueToken()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't create webhook secret")
+		return
+	}
+
+	endpointParams := database.CreateWebhookEndpointParams{
+		UserID:     p.userID,
+		ClientID:   uuid.NullUUID{UUID: p.clientID, Valid: p.clientID != uuid.Nil},
+		Url:        params.URL,
+		Secret:     webhookSecretPrefix + secret,
+		EventTypes: events,
+	}
+
+	endpoint, err := cfg.db.CreateWebhookEndpoint(r.Context(), endpointParams)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't create webhook endpoint in DB")
+		return
+	}
+
+	// The secret is only ever shown in this response.
+	rv := newWebhookEndpointResponse(endpoint)
+	rv.Secret = endpoint.Secret
+
+	respondWithJSON(w, http.StatusCreated, rv)
+}
+
+func (cfg *apiConfig) handleGetWebhookEndpoints(w http.ResponseWriter, r *http.Request) {
+	p, ok := cfg.authenticate(w, r, scopeWebhooks)
+	if !ok {
+		return
+	}
+
+	endpoints, err := cfg.db.GetWebhookEndpointsForUser(r.Context(), p.userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't get webhook endpoints")
+		return
+	}
+
+	rv := []webhookEndpointResponse{}
+	for _, endpoint := range endpoints {
+		rv = append(rv, newWebhookEndpointResponse(endpoint))
+	}
+
+	respondWithJSON(w, http.StatusOK, rv)
+}
+
+func (cfg *apiConfig) handleDeleteWebhookEndpoint(w http.ResponseWriter, r *http.Request) {
+	p, ok := cfg.authenticate(w, r, scopeWebhooks)
+	if !ok {
+		return
+	}
+
+	endpointID, err := uuid.Parse(r.PathValue("endpointID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "couldn't parse webhook endpoint ID")
+		return
+	}
+
+	deleteParams := database.DeleteWebhookEndpointParams{
+		ID:     endpointID,
+		UserID: p.userID,
+	}
+
+	deleted, err := cfg.db.DeleteWebhookEndpoint(r.Context(), deleteParams)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't delete webhook endpoint")
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "webhook endpoint does not exist")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getWebhookEndpoint returns the caller's endpoint named in the path,
+// responding with an error if there's no such endpoint.
+func (cfg *apiConfig) getWebhookEndpoint(w http.ResponseWriter, r *http.Request, p principal) (database.WebhookEndpoint, bool) {
+	endpointID, err := uuid.Parse(r.PathValue("endpointID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "couldn't parse webhook endpoint ID")
+		return database.WebhookEndpoint{}, false
+	}
+
+	endpointParams := database.GetWebhookEndpointForUserParams{
+		ID:     endpointID,
+		UserID: p.userID,
+	}
+
+	endpoint, err := cfg.db.GetWebhookEndpointForUser(r.Context(), endpointParams)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "webhook endpoint does not exist")
+		return endpoint, false
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't get webhook endpoint")
+		return endpoint, false
+	}
+
+	return endpoint, true
+}
+
+// handleTestWebhookEndpoint queues a ping event for an endpoint, so its
+// owner can check that it receives and verifies deliveries.
+func (cfg *apiConfig) handleTestWebhookEndpoint(w http.ResponseWriter, r *http.Request) {
+	p, ok := cfg.authenticate(w, r, scopeWebhooks)
+	if !ok {
+		return
+	}
+
+	endpoint, ok := cfg.getWebhookEndpoint(w, r, p)
+	if !ok {
+		return
+	}
+
+	eventID := uuid.New()
+	payload, err := newWebhookPayload(eventID, webhookEventPing, map[string]any{
+		"endpoint_id": endpoint.ID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't create test event")
+		return
+	}
+
+	deliveryParams := database.CreateWebhookDeliveryParams{
+		EndpointID: endpoint.ID,
+		EventID:    eventID,
+		EventType:  webhookEventPing,
+		Payload:    payload,
+	}
+
+	delivery, err := cfg.db.CreateWebhookDelivery(r.Context(), deliveryParams)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't queue test event")
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, newWebhookDeliveryResponse(delivery))
+}
+
+// handleGetWebhookDeliveries lists an endpoint's most recent deliveries
+// and how they went.
+func (cfg *apiConfig) handleGetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	p, ok := cfg.authenticate(w, r, scopeWebhooks)
+	if !ok {
+		return
+	}
+
+	endpoint, ok := cfg.getWebhookEndpoint(w, r, p)
+	if !ok {
+		return
+	}
+
+	deliveryParams := database.GetWebhookDeliveriesForEndpointParams{
+		EndpointID: endpoint.ID,
+		Limit:      webhookDeliveryLogLimit,
+	}
+
+	deliveries, err := cfg.db.GetWebhookDeliveriesForEndpoint(r.Context(), deliveryParams)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't get webhook deliveries")
+		return
+	}
+
+	rv := []webhookDeliveryResponse{}
+	for _, delivery := range deliveries {
+		rv = append(rv, newWebhookDeliveryResponse(delivery))
+	}
+
+	respondWithJSON(w, http.StatusOK, rv)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/M-Sviridov/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultWebhookEventLimit = 100
+	maxWebhookEventLimit     = 1000
+)
+
+type webhookEventResponse struct {
+	ID          uuid.UUID       `json:"id"`
+	Provider    string          `json:"provider"`
+	EventID     string          `json:"event_id"`
+	EventType   string          `json:"event_type"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Error       string          `json:"error,omitempty"`
+	Attempts    int32           `json:"attempts"`
+	ReceivedAt  time.Time       `json:"received_at"`
+	ProcessedAt *time.Time      `json:"processed_at"`
+}
+
+func newWebhookEventResponse(event database.WebhookEvent) webhookEventResponse {
+	rv := webhookEventResponse{
+		ID:         event.ID,
+		Provider:   event.Provider,
+		EventID:    event.EventID,
+		EventType:  event.EventType,
+		Payload:    event.Payload,
+		Status:     event.Status,
+		Error:      event.Error,
+		Attempts:   event.Attempts,
+		ReceivedAt: event.ReceivedAt,
+	}
+	if event.ProcessedAt.Valid {
+		rv.ProcessedAt = &event.ProcessedAt.Time
+	}
+	return rv
+}
+
+// handleGetWebhookEvents lists received webhook events, newest first,
+// optionally filtered by provider and status.
+func (cfg *apiConfig) handleGetWebhookEvents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	eventParams := database.GetWebhookEventsParams{
+		MaxResults: defaultWebhookEventLimit,
+	}
+
+	if v := query.Get("provider"); v != "" {
+		eventParams.Provider = sql.NullString{String: v, Valid: true}
+	}
+	if v := query.Get("status"); v != "" {
+		eventParams.Status = sql.NullString{String: v, Valid: true}
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxWebhookEventLimit {
+			respondWithError(w, http.StatusBadRequest, "limit must be between 1 and 1000")
+			return
+		}
+		eventParams.MaxResults = int32(limit)
+	}
+
+	events, err := cfg.db.GetWebhookEvents(r.Context(), eventParams)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't get webhook events")
+		return
+	}
+
+	rv := []webhookEventResponse{}
+	for _, event := range events {
+		rv = append(rv, newWebhookEventResponse(event))
+	}
+
+	respondWithJSON(w, http.StatusOK, rv)
+}
+
+// handleReplayWebhookEvent processes a failed webhook event again, once
+// whatever made it fail has been fixed. An event whose processing stalled
+// can be replayed too once its claim runs out.
+func (cfg *apiConfig) handleReplayWebhookEvent(w http.ResponseWriter, r *http.Request) {
+	eventID, err := uuid.Parse(r.PathValue("eventID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "couldn't parse webhook event ID")
+		return
+	}
+
+	replayParams := database.ReplayWebhookEventParams{
+		ID:           eventID,
+		LeaseSeconds: int32(webhookEventLease.Seconds()),
+	}
+	event, err := cfg.db.ReplayWebhookEvent(r.Context(), replayParams)
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, err := cfg.db.GetWebhookEvent(r.Context(), eventID); errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, "webhook event does not exist")
+			return
+		}
+		respondWithError(w, http.StatusConflict, "only failed or stalled webhook events can be replayed")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't replay webhook event")
+		return
+	}
+
+	// A replay that fails again is recorded as such; the caller reads the
+	// outcome from the event.
+	event, _ = cfg.processWebhookEvent(r, event)
+
+	respondWithJSON(w, http.StatusOK, newWebhookEventResponse(event))
+}
@@ -1,70 +1,69 @@
 package main
 
 import (
-	"encoding/json"
+	"io"
 	"net/http"
 
-	"github.com/M-Sviridov/chirpy/internal/auth"
-	"github.com/google/uuid"
+	"github.com/M-Sviridov/chirpy/internal/database"
 )
 
+// handleWebhooks receives a payment provider's webhook deliveries. The
+// provider is named in the path.
 func (cfg *apiConfig) handleWebhooks(w http.ResponseWriter, r *http.Request) {
-	type parameters struct {
-		Event string `json:"event"`
-		Data  struct {
-			UserID string `json:"user_id"`
-		} `json:"data"`
+	provider, ok := cfg.paymentProviders[r.PathValue("provider")]
+	if !ok {
+		respondWithError(w, http.StatusNotFound, "unknown payment provider")
+		return
 	}
 
-	// type respVals struct {
-	// 	ID        uuid.UUID `json:"id"`
-	// 	CreatedAt time.Time `json:"created_at"`
-	// 	UpdatedAt time.Time `json:"updated_at"`
-	// 	Email     string    `json:"email"`
-	// }
-
-	decoder := json.NewDecoder(r.Body)
-	params := parameters{}
-	err := decoder.Decode(&params)
+	// Signatures cover the exact bytes the provider sent, so read them
+	// before decoding.
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodyBytes))
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "error decoding parameters")
+		respondWithError(w, http.StatusRequestEntityTooLarge, "couldn't read webhook body")
 		return
 	}
 
-	apiKey, err := auth.GetAPIKey(r.Header)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "couldn't get api key")
+	if err := provider.VerifyWebhook(r.Header, body); err != nil {
+		respondWithError(w, http.StatusUnauthorized, "couldn't verify webhook")
 		return
 	}
-	if apiKey != cfg.polkaKey {
-		respondWithError(w, http.StatusUnauthorized, "couldn't get api key")
+
+	paymentEvent, err := provider.ParseEvent(body)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "error decoding parameters")
 		return
 	}
 
-	if params.Event != "user.upgraded" {
-		w.WriteHeader(http.StatusNoContent)
-		return
+	eventParams := database.CreateWebhookEventParams{
+		Provider:  provider.Name(),
+		EventID:   paymentEvent.LedgerID(),
+		EventType: paymentEvent.ProviderType,
+		Payload:   body,
 	}
 
-	userID, err := uuid.Parse(params.Data.UserID)
+	event, claimed, err := cfg.claimWebhookEvent(r.Context(), eventParams)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "couldn't parse user ID")
+		respondWithError(w, http.StatusInternalServerError, "couldn't record webhook event")
+		return
+	}
+	if !claimed {
+		// A retry of an event that has been or is being handled.
+		w.WriteHeader(http.StatusNoContent)
 		return
 	}
 
-	if err := cfg.db.UpgradeUserToChirpyRed(r.Context(), userID); err != nil {
-		respondWithError(w, http.StatusNotFound, "couldn't upgrade user to chirpy red")
+	// Failing here makes the provider deliver the event again later.
+	if _, err := cfg.processWebhookEvent(r, event); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't process webhook event")
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
+}
 
-	// rv := respVals{
-	// 	ID:        user.ID,
-	// 	CreatedAt: user.CreatedAt,
-	// 	UpdatedAt: user.UpdatedAt,
-	// 	Email:     user.Email,
-	// }
-
-	// respondWithJSON(w, http.StatusCreated, rv)
+// handlePolkaWebhooks serves the URL Polka was first set up with.
+func (cfg *apiConfig) handlePolkaWebhooks(w http.ResponseWriter, r *http.Request) {
+	r.SetPathValue("provider", "polka")
+	cfg.handleWebhooks(w, r)
 }
@@ -1,7 +1,9 @@
 package auth
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -13,15 +15,77 @@ import (
 	"github.com/google/uuid"
 )
 
-func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
-	claims := &jwt.RegisteredClaims{
-		Issuer:    "chirpy",
-		Subject:   userID.String(),
-		ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
-		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+func init() {
+	// Issue times are compared with the time a user's tokens were revoked,
+	// so whole seconds can't tell a token issued just before a revocation
+	// from one issued just after it.
+	jwt.TimePrecision = time.Millisecond
+}
+
+// Claims are the claims carried by Chirpy access tokens.
+type Claims struct {
+	jwt.RegisteredClaims
+	SessionID string `json:"sid,omitempty"`
+	// TokenUse is empty for access tokens and set for tokens that must not
+	// be accepted as access tokens, such as two-factor login challenges.
+	TokenUse string `json:"token_use,omitempty"`
+	// ClientID and Scope are set on access tokens issued to OAuth clients,
+	// which may only do what the user consented to. Scope is space
+	// separated, as in RFC 9068.
+	ClientID string `json:"client_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+	// Role is the user's role, set on first-party access tokens only.
+	Role string `json:"role,omitempty"`
+}
+
+// Scopes returns the scopes an access token was granted.
+func (c *Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+const tokenUseTwoFactorChallenge = "2fa_challenge"
+
+func MakeJWT(userID, sessionID uuid.UUID, keys *Keyring, expiresIn time.Duration) (string, error) {
+	return MakeAccessToken(AccessTokenParams{
+		UserID:    userID,
+		SessionID: sessionID,
+		ExpiresIn: expiresIn,
+	}, keys)
+}
+
+// AccessTokenParams describe an access token to issue. SessionID and
+// ClientID may be nil; Scopes only apply to tokens for OAuth clients.
+type AccessTokenParams struct {
+	UserID    uuid.UUID
+	SessionID uuid.UUID
+	ClientID  uuid.UUID
+	Scopes    []string
+	Role      string
+	ExpiresIn time.Duration
+}
+
+// MakeAccessToken issues an access token. Tokens for OAuth clients are
+// limited to their scopes and never carry the user's role.
+func MakeAccessToken(params AccessTokenParams, keys *Keyring) (string, error) {
+	claims := &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Issuer:    "chirpy",
+			Subject:   params.UserID.String(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(params.ExpiresIn)),
+			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+		},
+	}
+	if params.SessionID != uuid.Nil {
+		claims.SessionID = params.SessionID.String()
+	}
+	if params.ClientID != uuid.Nil {
+		claims.ClientID = params.ClientID.String()
+		claims.Scope = strings.Join(params.Scopes, " ")
+	} else {
+		claims.Role = params.Role
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	ss, err := token.SignedString([]byte(tokenSecret))
+	ss, err := keys.sign(claims)
 	if err != nil {
 		return "", err
 	}
@@ -29,36 +93,98 @@ func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (str
 	return ss, nil
 }
 
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	claims := &jwt.RegisteredClaims{}
-	token, err := jwt.ParseWithClaims(
+// ParseJWT validates an access token and returns its claims.
+func ParseJWT(tokenString string, keys *Keyring) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(
 		tokenString,
 		claims,
-		func(token *jwt.Token) (any, error) { return []byte(tokenSecret), nil })
+		keys.verificationKey,
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt())
 	if err != nil {
-		return uuid.Nil, err
+		return nil, err
 	}
 
-	userID, err := token.Claims.GetSubject()
-	if err != nil {
-		return uuid.Nil, err
+	if claims.IssuedAt == nil {
+		return nil, errors.New("missing issued at")
+	}
+
+	if claims.TokenUse != "" {
+		return nil, errors.New("not an access token")
+	}
+
+	if claims.Issuer != "chirpy" {
+		return nil, errors.New("invalid issuer")
+	}
+
+	if _, err := uuid.Parse(claims.Subject); err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	if claims.SessionID != "" {
+		if _, err := uuid.Parse(claims.SessionID); err != nil {
+			return nil, fmt.Errorf("invalid session ID: %w", err)
+		}
+	}
+
+	if claims.ClientID != "" {
+		if _, err := uuid.Parse(claims.ClientID); err != nil {
+			return nil, fmt.Errorf("invalid client ID: %w", err)
+		}
 	}
 
-	issuer, err := token.Claims.GetIssuer()
+	return claims, nil
+}
+
+func ValidateJWT(tokenString string, keys *Keyring) (uuid.UUID, error) {
+	claims, err := ParseJWT(tokenString, keys)
 	if err != nil {
 		return uuid.Nil, err
 	}
 
-	if issuer != "chirpy" {
-		return uuid.Nil, errors.New("invalid issuer")
+	return uuid.Parse(claims.Subject)
+}
+
+// MakeChallengeJWT issues a token proving the user passed the password step
+// of a login that still needs a second factor.
+func MakeChallengeJWT(userID uuid.UUID, keys *Keyring, expiresIn time.Duration) (string, error) {
+	claims := &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Issuer:    "chirpy",
+			Subject:   userID.String(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
+			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+		},
+		TokenUse: tokenUseTwoFactorChallenge,
 	}
 
-	id, err := uuid.Parse(userID)
+	return keys.sign(claims)
+}
+
+// ParseChallengeJWT validates a token issued by MakeChallengeJWT.
+func ParseChallengeJWT(tokenString string, keys *Keyring) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(
+		tokenString,
+		claims,
+		keys.verificationKey,
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuer("chirpy"))
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("invalid user ID: %w", err)
+		return nil, err
+	}
+
+	if claims.TokenUse != tokenUseTwoFactorChallenge {
+		return nil, errors.New("not a two-factor challenge token")
 	}
 
-	return id, nil
+	if _, err := uuid.Parse(claims.Subject); err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	return claims, nil
 }
 
 func GetBearerToken(headers http.Header) (string, error) {
@@ -76,6 +202,12 @@ func GetBearerToken(headers http.Header) (string, error) {
 }
 
 func MakeRefreshToken() (string, error) {
+	return MakeOpaqueToken()
+}
+
+// MakeOpaqueToken returns a random hex encoded token with 256 bits of
+// entropy, for use in links and other single-use secrets.
+func MakeOpaqueToken() (string, error) {
 	token := make([]byte, 32)
 	_, err := rand.Read(token)
 	if err != nil {
@@ -85,6 +217,34 @@ func MakeRefreshToken() (string, error) {
 	return hex.EncodeToString(token), nil
 }
 
+// PersonalAccessTokenPrefix starts every personal access token, so they
+// can be told apart from JWTs and spotted by secret scanners.
+const PersonalAccessTokenPrefix = "chirpy_pat_"
+
+// MakePersonalAccessToken returns a new random personal access token.
+func MakePersonalAccessToken() (string, error) {
+	token, err := MakeOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	return PersonalAccessTokenPrefix + token, nil
+}
+
+// IsPersonalAccessToken reports whether a bearer token is a personal access
+// token rather than a JWT.
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, PersonalAccessTokenPrefix)
+}
+
+// HashToken returns the hex encoded HMAC-SHA256 of token keyed with key.
+// Opaque tokens are only ever stored in this form.
+func HashToken(token, key string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(token))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
 func GetAPIKey(headers http.Header) (string, error) {
 	header := headers.Get("Authorization")
 	if header == "" {
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -10,33 +11,35 @@ import (
 
 func TestValidateJWT(t *testing.T) {
 	userID := uuid.New()
-	validToken, _ := MakeJWT(userID, "password", time.Hour)
+	keys, _ := NewHMACKeyring("password")
+	invalidKeys, _ := NewHMACKeyring("invalidtokensecret")
+	validToken, _ := MakeJWT(userID, uuid.Nil, keys, time.Hour)
 
 	validateJWTTests := []struct {
 		name        string
 		tokenString string
-		tokenSecret string
+		keys        *Keyring
 		hasUserID   uuid.UUID
 		hasErr      bool
 	}{
 		{
 			name:        "Valid token",
 			tokenString: validToken,
-			tokenSecret: "password",
+			keys:        keys,
 			hasUserID:   userID,
 			hasErr:      false,
 		},
 		{
 			name:        "Invalid token",
 			tokenString: "invalidtokenstring",
-			tokenSecret: "password",
+			keys:        keys,
 			hasUserID:   uuid.Nil,
 			hasErr:      true,
 		},
 		{
 			name:        "Invalid token secret",
 			tokenString: validToken,
-			tokenSecret: "invalidtokensecret",
+			keys:        invalidKeys,
 			hasUserID:   uuid.Nil,
 			hasErr:      true,
 		},
@@ -44,7 +47,7 @@ func TestValidateJWT(t *testing.T) {
 
 	for _, tt := range validateJWTTests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotUserID, err := ValidateJWT(tt.tokenString, tt.tokenSecret)
+			gotUserID, err := ValidateJWT(tt.tokenString, tt.keys)
 			if (err != nil) != tt.hasErr {
 				t.Errorf("ValidateJWT() error = %v, wantErr %v", err, tt.hasErr)
 			}
@@ -101,3 +104,232 @@ func TestGetBearerToken(t *testing.T) {
 		})
 	}
 }
+
+func TestHashToken(t *testing.T) {
+	hashTokenTests := []struct {
+		name       string
+		token      string
+		key        string
+		otherToken string
+		otherKey   string
+		wantSame   bool
+	}{
+		{
+			name:       "Same token and key",
+			token:      "tokenx10202",
+			key:        "key",
+			otherToken: "tokenx10202",
+			otherKey:   "key",
+			wantSame:   true,
+		},
+		{
+			name:       "Different token",
+			token:      "tokenx10202",
+			key:        "key",
+			otherToken: "tokenx10203",
+			otherKey:   "key",
+			wantSame:   false,
+		},
+		{
+			name:       "Different key",
+			token:      "tokenx10202",
+			key:        "key",
+			otherToken: "tokenx10202",
+			otherKey:   "otherkey",
+			wantSame:   false,
+		},
+	}
+
+	for _, tt := range hashTokenTests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := HashToken(tt.token, tt.key)
+			if got == tt.token {
+				t.Errorf("HashToken() returned the token unchanged")
+			}
+			if (got == HashToken(tt.otherToken, tt.otherKey)) != tt.wantSame {
+				t.Errorf("HashToken() equal = %v, want %v", !tt.wantSame, tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestIsPersonalAccessToken(t *testing.T) {
+	pat, err := MakePersonalAccessToken()
+	if err != nil {
+		t.Fatalf("MakePersonalAccessToken() error = %v", err)
+	}
+	keys, err := NewHMACKeyring("secret")
+	if err != nil {
+		t.Fatalf("NewHMACKeyring() error = %v", err)
+	}
+	jwtToken, err := MakeJWT(uuid.New(), uuid.Nil, keys, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT() error = %v", err)
+	}
+
+	isPATTests := []struct {
+		name  string
+		token string
+		isPAT bool
+	}{
+		{name: "Personal access token", token: pat, isPAT: true},
+		{name: "JWT", token: jwtToken, isPAT: false},
+		{name: "Refresh token", token: strings.TrimPrefix(pat, PersonalAccessTokenPrefix), isPAT: false},
+	}
+
+	for _, tt := range isPATTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsPersonalAccessToken(tt.token); got != tt.isPAT {
+				t.Errorf("IsPersonalAccessToken() = %v, want %v", got, tt.isPAT)
+			}
+		})
+	}
+}
+
+func TestParseJWT(t *testing.T) {
+	userID := uuid.New()
+	sessionID := uuid.New()
+	keys, _ := NewHMACKeyring("password")
+
+	parseJWTTests := []struct {
+		name         string
+		sessionID    uuid.UUID
+		hasSessionID string
+	}{
+		{
+			name:         "Token with session",
+			sessionID:    sessionID,
+			hasSessionID: sessionID.String(),
+		},
+		{
+			name:         "Token without session",
+			sessionID:    uuid.Nil,
+			hasSessionID: "",
+		},
+	}
+
+	for _, tt := range parseJWTTests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := MakeJWT(userID, tt.sessionID, keys, time.Hour)
+			if err != nil {
+				t.Fatalf("MakeJWT() error = %v", err)
+			}
+
+			claims, err := ParseJWT(token, keys)
+			if err != nil {
+				t.Fatalf("ParseJWT() error = %v", err)
+			}
+			if claims.Subject != userID.String() {
+				t.Errorf("ParseJWT() subject = %v, want %v", claims.Subject, userID)
+			}
+			if claims.SessionID != tt.hasSessionID {
+				t.Errorf("ParseJWT() session ID = %q, want %q", claims.SessionID, tt.hasSessionID)
+			}
+			if _, err := uuid.Parse(claims.ID); err != nil {
+				t.Errorf("ParseJWT() jti = %q, want a UUID", claims.ID)
+			}
+		})
+	}
+}
+
+func TestMakeAccessToken(t *testing.T) {
+	userID := uuid.New()
+	clientID := uuid.New()
+	keys, _ := NewHMACKeyring("password")
+
+	makeAccessTokenTests := []struct {
+		name        string
+		params      AccessTokenParams
+		hasClientID string
+		hasScopes   string
+		hasRole     string
+	}{
+		{
+			name:    "First-party token",
+			params:  AccessTokenParams{UserID: userID, SessionID: uuid.New(), Role: "admin", ExpiresIn: time.Hour},
+			hasRole: "admin",
+		},
+		{
+			name: "Client token",
+			params: AccessTokenParams{
+				UserID:    userID,
+				SessionID: uuid.New(),
+				ClientID:  clientID,
+				Scopes:    []string{"chirps:read", "profile:write"},
+				Role:      "admin",
+				ExpiresIn: time.Hour,
+			},
+			hasClientID: clientID.String(),
+			hasScopes:   "chirps:read,profile:write",
+		},
+	}
+
+	for _, tt := range makeAccessTokenTests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := MakeAccessToken(tt.params, keys)
+			if err != nil {
+				t.Fatalf("MakeAccessToken() error = %v", err)
+			}
+
+			claims, err := ParseJWT(token, keys)
+			if err != nil {
+				t.Fatalf("ParseJWT() error = %v", err)
+			}
+			if claims.ClientID != tt.hasClientID {
+				t.Errorf("ParseJWT() client ID = %q, want %q", claims.ClientID, tt.hasClientID)
+			}
+			if got := strings.Join(claims.Scopes(), ","); got != tt.hasScopes {
+				t.Errorf("ParseJWT() scopes = %q, want %q", got, tt.hasScopes)
+			}
+			if claims.Role != tt.hasRole {
+				t.Errorf("ParseJWT() role = %q, want %q", claims.Role, tt.hasRole)
+			}
+		})
+	}
+}
+
+func TestAccessTokenIssuedAtPrecision(t *testing.T) {
+	keys, _ := NewHMACKeyring("password")
+
+	// Start partway through a second, so second precision would show.
+	if time.Now().Nanosecond() < int(time.Millisecond) {
+		time.Sleep(time.Millisecond)
+	}
+	before := time.Now().Truncate(time.Millisecond)
+
+	token, err := MakeAccessToken(AccessTokenParams{UserID: uuid.New(), ExpiresIn: time.Hour}, keys)
+	if err != nil {
+		t.Fatalf("MakeAccessToken() error = %v", err)
+	}
+	claims, err := ParseJWT(token, keys)
+	if err != nil {
+		t.Fatalf("ParseJWT() error = %v", err)
+	}
+
+	if claims.IssuedAt.Time.Before(before) {
+		t.Errorf("ParseJWT() issued at = %v, want no earlier than %v", claims.IssuedAt.Time, before)
+	}
+}
+
+func TestChallengeJWT(t *testing.T) {
+	userID := uuid.New()
+	keys, _ := NewHMACKeyring("password")
+	challengeToken, _ := MakeChallengeJWT(userID, keys, time.Minute)
+	accessToken, _ := MakeJWT(userID, uuid.Nil, keys, time.Minute)
+
+	if _, err := ParseJWT(challengeToken, keys); err == nil {
+		t.Errorf("ParseJWT() accepted a challenge token")
+	}
+
+	if _, err := ParseChallengeJWT(accessToken, keys); err == nil {
+		t.Errorf("ParseChallengeJWT() accepted an access token")
+	}
+
+	claims, err := ParseChallengeJWT(challengeToken, keys)
+	if err != nil {
+		t.Fatalf("ParseChallengeJWT() error = %v", err)
+	}
+	if claims.Subject != userID.String() {
+		t.Errorf("ParseChallengeJWT() subject = %v, want %v", claims.Subject, userID)
+	}
+}
@@ -0,0 +1,251 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key is a JWT signing key identified by the kid header of the tokens it
+// signs. Keys holding only a public key can verify tokens but not sign them.
+type Key struct {
+	ID        string
+	Algorithm string
+	signKey   any
+	verifyKey any
+}
+
+// NewKey wraps an HMAC secret ([]byte), an Ed25519 key or an RSA key. The
+// signing algorithm is derived from the key type.
+func NewKey(id string, key any) (Key, error) {
+	switch k := key.(type) {
+	case []byte:
+		if len(k) == 0 {
+			return Key{}, errors.New("empty HMAC secret")
+		}
+		return Key{ID: id, Algorithm: jwt.SigningMethodHS256.Alg(), signKey: k, verifyKey: k}, nil
+	case ed25519.PrivateKey:
+		return Key{ID: id, Algorithm: jwt.SigningMethodEdDSA.Alg(), signKey: k, verifyKey: k.Public()}, nil
+	case ed25519.PublicKey:
+		return Key{ID: id, Algorithm: jwt.SigningMethodEdDSA.Alg(), verifyKey: k}, nil
+	case *rsa.PrivateKey:
+		return Key{ID: id, Algorithm: jwt.SigningMethodRS256.Alg(), signKey: k, verifyKey: &k.PublicKey}, nil
+	case *rsa.PublicKey:
+		return Key{ID: id, Algorithm: jwt.SigningMethodRS256.Alg(), verifyKey: k}, nil
+	default:
+		return Key{}, fmt.Errorf("unsupported key type %T", key)
+	}
+}
+
+// LoadKey builds a key from its configured value. HS256 values are the
+// secret itself, EdDSA and RS256 values are paths to PEM encoded keys.
+func LoadKey(id, algorithm, value string) (Key, error) {
+	var key Key
+	var err error
+	switch algorithm {
+	case jwt.SigningMethodHS256.Alg():
+		key, err = NewKey(id, []byte(value))
+	case jwt.SigningMethodEdDSA.Alg(), jwt.SigningMethodRS256.Alg():
+		key, err = loadPEMKey(id, value)
+	default:
+		return Key{}, fmt.Errorf("unsupported algorithm %q", algorithm)
+	}
+	if err != nil {
+		return Key{}, err
+	}
+
+	if key.Algorithm != algorithm {
+		return Key{}, fmt.Errorf("key %q is not a %s key", id, algorithm)
+	}
+
+	return key, nil
+}
+
+func loadPEMKey(id, path string) (Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Key{}, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return Key{}, fmt.Errorf("no PEM data in %s", path)
+	}
+
+	var key any
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return Key{}, fmt.Errorf("unsupported PEM block %q in %s", block.Type, path)
+	}
+	if err != nil {
+		return Key{}, err
+	}
+
+	return NewKey(id, key)
+}
+
+// Keyring holds the key new tokens are signed with and the older keys that
+// are still accepted when verifying tokens.
+type Keyring struct {
+	active *Key
+	keys   map[string]Key
+}
+
+// NewKeyring creates a keyring that signs with the key identified by
+// activeID.
+func NewKeyring(activeID string, keys ...Key) (*Keyring, error) {
+	k := &Keyring{keys: map[string]Key{}}
+	for _, key := range keys {
+		if _, ok := k.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate key %q", key.ID)
+		}
+		k.keys[key.ID] = key
+	}
+
+	active, ok := k.keys[activeID]
+	if !ok {
+		return nil, fmt.Errorf("active key %q not found", activeID)
+	}
+	if active.signKey == nil {
+		return nil, fmt.Errorf("active key %q cannot sign", activeID)
+	}
+	k.active = &active
+
+	return k, nil
+}
+
+// NewHMACKeyring creates a keyring with a single HS256 secret and no kid, the
+// way tokens were signed before key rotation was supported.
+func NewHMACKeyring(secret string) (*Keyring, error) {
+	key, err := NewKey("", []byte(secret))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewKeyring("", key)
+}
+
+// LoadKeyring parses a comma separated list of kid:algorithm:value entries,
+// see LoadKey. A non-empty legacySecret is added as an HS256 key without kid
+// so tokens issued before rotation keep verifying; it signs only if no other
+// keys are configured.
+func LoadKeyring(spec, activeID, legacySecret string) (*Keyring, error) {
+	keys := []Key{}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 || parts[0] == "" {
+			return nil, fmt.Errorf("malformed key entry %q", entry)
+		}
+
+		key, err := LoadKey(parts[0], parts[1], parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("couldn't load key %q: %w", parts[0], err)
+		}
+		keys = append(keys, key)
+	}
+
+	if legacySecret != "" {
+		key, err := NewKey("", []byte(legacySecret))
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no signing keys configured")
+	}
+	if activeID == "" && len(keys) > 1 {
+		return nil, errors.New("active key must be set when several keys are configured")
+	}
+	if activeID == "" {
+		activeID = keys[0].ID
+	}
+
+	return NewKeyring(activeID, keys...)
+}
+
+func (k *Keyring) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(k.active.Algorithm), claims)
+	if k.active.ID != "" {
+		token.Header["kid"] = k.active.ID
+	}
+
+	return token.SignedString(k.active.signKey)
+}
+
+func (k *Keyring) verificationKey(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("unexpected signing method %q", token.Method.Alg())
+	}
+
+	return key.verifyKey, nil
+}
+
+// JWK is a public key in JSON Web Key format.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+// PublicJWKs returns the public keys of the keyring's asymmetric keys. HMAC
+// secrets are never published.
+func (k *Keyring) PublicJWKs() []JWK {
+	jwks := []JWK{}
+	for _, key := range k.keys {
+		jwk := JWK{
+			Kid: key.ID,
+			Use: "sig",
+			Alg: key.Algorithm,
+		}
+
+		switch pub := key.verifyKey.(type) {
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		default:
+			continue
+		}
+
+		jwks = append(jwks, jwk)
+	}
+
+	return jwks
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestKeyringRotation(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	oldKey, _ := NewKey("old", []byte("password"))
+	edSigningKey, _ := NewKey("ed", edKey)
+	rsaSigningKey, _ := NewKey("rsa", rsaKey)
+
+	oldKeyring, _ := NewKeyring("old", oldKey)
+	rotatedKeyring, _ := NewKeyring("ed", oldKey, edSigningKey, rsaSigningKey)
+	retiredKeyring, _ := NewKeyring("rsa", edSigningKey, rsaSigningKey)
+
+	userID := uuid.New()
+	oldToken, _ := MakeJWT(userID, uuid.Nil, oldKeyring, time.Hour)
+	edToken, _ := MakeJWT(userID, uuid.Nil, rotatedKeyring, time.Hour)
+
+	keyringTests := []struct {
+		name        string
+		tokenString string
+		keys        *Keyring
+		hasErr      bool
+	}{
+		{
+			name:        "Token signed with active key",
+			tokenString: edToken,
+			keys:        rotatedKeyring,
+			hasErr:      false,
+		},
+		{
+			name:        "Token signed with previous key",
+			tokenString: oldToken,
+			keys:        rotatedKeyring,
+			hasErr:      false,
+		},
+		{
+			name:        "Token signed with retired key",
+			tokenString: oldToken,
+			keys:        retiredKeyring,
+			hasErr:      true,
+		},
+		{
+			name:        "Token signed with other asymmetric key",
+			tokenString: edToken,
+			keys:        retiredKeyring,
+			hasErr:      false,
+		},
+	}
+
+	for _, tt := range keyringTests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotUserID, err := ValidateJWT(tt.tokenString, tt.keys)
+			if (err != nil) != tt.hasErr {
+				t.Errorf("ValidateJWT() error = %v, wantErr %v", err, tt.hasErr)
+				return
+			}
+			if !tt.hasErr && gotUserID != userID {
+				t.Errorf("ValidateJWT() gotUserID = %v, want %v", gotUserID, userID)
+			}
+		})
+	}
+}
+
+func TestPublicJWKs(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	hmacKey, _ := NewKey("hmac", []byte("password"))
+	edSigningKey, _ := NewKey("ed", edKey)
+	keys, _ := NewKeyring("ed", hmacKey, edSigningKey)
+
+	jwks := keys.PublicJWKs()
+	if len(jwks) != 1 {
+		t.Fatalf("PublicJWKs() returned %d keys, want 1", len(jwks))
+	}
+	if jwks[0].Kid != "ed" || jwks[0].Kty != "OKP" || jwks[0].Alg != "EdDSA" {
+		t.Errorf("PublicJWKs() = %+v, want the Ed25519 key", jwks[0])
+	}
+}
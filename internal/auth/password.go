@@ -1,9 +1,13 @@
 package auth
 
-import "github.com/alexedwards/argon2id"
+import (
+	"time"
 
-func HashPassword(password string) (string, error) {
-	hash, err := argon2id.CreateHash(password, argon2id.DefaultParams)
+	"github.com/alexedwards/argon2id"
+)
+
+func HashPassword(password string, params *argon2id.Params) (string, error) {
+	hash, err := argon2id.CreateHash(password, params)
 	if err != nil {
 		return "", err
 	}
@@ -19,3 +23,66 @@ func CheckPasswordHash(password, hash string) (bool, error) {
 
 	return match, nil
 }
+
+// NeedsRehash reports whether hash was created with weaker parameters than
+// params, in which case it should be replaced next time the password is
+// known.
+func NeedsRehash(hash string, params *argon2id.Params) (bool, error) {
+	current, _, _, err := argon2id.DecodeHash(hash)
+	if err != nil {
+		return false, err
+	}
+
+	return current.Memory < params.Memory ||
+		current.Iterations < params.Iterations ||
+		current.Parallelism < params.Parallelism ||
+		current.SaltLength < params.SaltLength ||
+		current.KeyLength < params.KeyLength, nil
+}
+
+// CalibratePasswordParams finds the number of iterations for which hashing
+// a password with the given memory (in KiB) and parallelism takes at least
+// target on this machine. It returns the parameters and the time one hash
+// took with them.
+func CalibratePasswordParams(target time.Duration, memory uint32, parallelism uint8) (*argon2id.Params, time.Duration, error) {
+	params := &argon2id.Params{
+		Memory:      memory,
+		Iterations:  1,
+		Parallelism: parallelism,
+		SaltLength:  argon2id.DefaultParams.SaltLength,
+		KeyLength:   argon2id.DefaultParams.KeyLength,
+	}
+
+	for {
+		elapsed, err := timeHash(params)
+		if err != nil {
+			return nil, 0, err
+		}
+		if elapsed >= target {
+			return params, elapsed, nil
+		}
+
+		params.Iterations++
+	}
+}
+
+// timeHash returns the fastest of a few hashes, to smooth out scheduling
+// noise.
+func timeHash(params *argon2id.Params) (time.Duration, error) {
+	const runs = 3
+
+	var fastest time.Duration
+	for i := range runs {
+		start := time.Now()
+		if _, err := argon2id.CreateHash("chirpy-calibration", params); err != nil {
+			return 0, err
+		}
+
+		elapsed := time.Since(start)
+		if i == 0 || elapsed < fastest {
+			fastest = elapsed
+		}
+	}
+
+	return fastest, nil
+}
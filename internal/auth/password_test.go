@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/alexedwards/argon2id"
+)
+
+func TestNeedsRehash(t *testing.T) {
+	weakParams := &argon2id.Params{Memory: 8 * 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	strongParams := &argon2id.Params{Memory: 16 * 1024, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+	weakHash, _ := HashPassword("password", weakParams)
+	strongHash, _ := HashPassword("password", strongParams)
+
+	needsRehashTests := []struct {
+		name           string
+		hash           string
+		params         *argon2id.Params
+		hasNeedsRehash bool
+		hasErr         bool
+	}{
+		{name: "Weaker hash", hash: weakHash, params: strongParams, hasNeedsRehash: true},
+		{name: "Same parameters", hash: strongHash, params: strongParams, hasNeedsRehash: false},
+		{name: "Stronger hash", hash: strongHash, params: weakParams, hasNeedsRehash: false},
+		{name: "Invalid hash", hash: "unset", params: strongParams, hasErr: true},
+	}
+
+	for _, tt := range needsRehashTests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NeedsRehash(tt.hash, tt.params)
+			if (err != nil) != tt.hasErr {
+				t.Errorf("NeedsRehash() error = %v, wantErr %v", err, tt.hasErr)
+			}
+			if got != tt.hasNeedsRehash {
+				t.Errorf("NeedsRehash() = %v, want %v", got, tt.hasNeedsRehash)
+			}
+		})
+	}
+}
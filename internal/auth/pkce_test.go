@@ -0,0 +1,30 @@
+package auth
+
+import "testing"
+
+func TestVerifyPKCE(t *testing.T) {
+	const challenge = "lR_rEIuv5bD_zVT_Gr_KP1D3BVUX06NjHLO5GTHkjHA"
+
+	verifyPKCETests := []struct {
+		name     string
+		verifier string
+		isValid  bool
+	}{
+		{name: "Matching verifier", verifier: "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFIEjXk", isValid: true},
+		{name: "Other verifier", verifier: "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFIEjXl", isValid: false},
+		{name: "Short verifier", verifier: "dBjftJeZ4CVP", isValid: false},
+		{name: "Empty verifier", verifier: "", isValid: false},
+	}
+
+	for _, tt := range verifyPKCETests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyPKCE(tt.verifier, challenge); got != tt.isValid {
+				t.Errorf("VerifyPKCE() = %v, want %v", got, tt.isValid)
+			}
+		})
+	}
+
+	if !ValidPKCEChallenge(challenge) {
+		t.Errorf("ValidPKCEChallenge() = false, want true")
+	}
+}
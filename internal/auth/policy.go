@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Rules a password can fail, as reported in PolicyViolation.Rule.
+const (
+	RuleMinLength   = "min_length"
+	RuleMaxLength   = "max_length"
+	RuleContextWord = "context_word"
+	RuleBreached    = "breached"
+)
+
+// breachedPrefixLength is how many hex digits of a hash select its range.
+const breachedPrefixLength = 5
+
+// PasswordPolicy describes which passwords users may choose.
+type PasswordPolicy struct {
+	MinLength int
+	MaxLength int
+	// BannedWords may not appear anywhere in a password, ignoring case.
+	BannedWords []string
+	// Breached, if set, rejects passwords known from public breaches.
+	Breached *BreachedPasswords
+}
+
+// PolicyViolation is one rule a password failed.
+type PolicyViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// CheckPassword returns every rule of the policy password breaks. The
+// context words, such as the user's email address, are banned in addition
+// to the policy's own.
+func (p PasswordPolicy) CheckPassword(password string, context ...string) []PolicyViolation {
+	var violations []PolicyViolation
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		violations = append(violations, PolicyViolation{
+			Rule:    RuleMinLength,
+			Message: fmt.Sprintf("password must be at least %d characters long", p.MinLength),
+		})
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, PolicyViolation{
+			Rule:    RuleMaxLength,
+			Message: fmt.Sprintf("password must be at most %d characters long", p.MaxLength),
+		})
+	}
+
+	lower := strings.ToLower(password)
+	words := make([]string, 0, len(p.BannedWords)+len(context))
+	words = append(words, p.BannedWords...)
+	words = append(words, context...)
+	for _, word := range words {
+		word = strings.ToLower(word)
+		// Very short words would ban too many reasonable passwords.
+		if utf8.RuneCountInString(word) < 3 || !strings.Contains(lower, word) {
+			continue
+		}
+		violations = append(violations, PolicyViolation{
+			Rule:    RuleContextWord,
+			Message: fmt.Sprintf("password must not contain %q", word),
+		})
+	}
+
+	if p.Breached != nil && p.Breached.Contains(password) {
+		violations = append(violations, PolicyViolation{
+			Rule:    RuleBreached,
+			Message: "password has appeared in a data breach",
+		})
+	}
+
+	return violations
+}
+
+// EmailContextWords returns the parts of an email address a password
+// shouldn't contain: the whole address and its local part.
+func EmailContextWords(email string) []string {
+	local, _, found := strings.Cut(email, "@")
+	if !found {
+		return []string{email}
+	}
+
+	return []string{email, local}
+}
+
+// BreachedPasswords is a corpus of SHA-1 hashes of breached passwords,
+// bucketed by the first five hex digits of the hash the way k-anonymity
+// range APIs are, so a lookup only ever touches one small range.
+type BreachedPasswords struct {
+	ranges map[string]map[string]struct{}
+}
+
+// LoadBreachedPasswords reads a corpus with one uppercase or lowercase
+// hex SHA-1 hash per line, optionally followed by ":count" as in the Pwned
+// Passwords downloads. Blank lines and lines starting with # are skipped.
+func LoadBreachedPasswords(r io.Reader) (*BreachedPasswords, error) {
+	b := &BreachedPasswords{ranges: map[string]map[string]struct{}{}}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		hash, _, _ := strings.Cut(entry, ":")
+		hash = strings.ToUpper(hash)
+		if len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("line %d: not a SHA-1 hash", line)
+		}
+		if _, err := hex.DecodeString(hash); err != nil {
+			return nil, fmt.Errorf("line %d: not a SHA-1 hash", line)
+		}
+
+		prefix, suffix := hash[:breachedPrefixLength], hash[breachedPrefixLength:]
+		if b.ranges[prefix] == nil {
+			b.ranges[prefix] = map[string]struct{}{}
+		}
+		b.ranges[prefix][suffix] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// Contains reports whether password is in the corpus.
+func (b *BreachedPasswords) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	_, found := b.ranges[hash[:breachedPrefixLength]][hash[breachedPrefixLength:]]
+	return found
+}
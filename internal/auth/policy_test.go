@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckPassword(t *testing.T) {
+	breached, err := LoadBreachedPasswords(strings.NewReader(
+		"# password1\nE38AD214943DAAD1D64C102FAEC29DE4AFE9DA3D:2413945\n",
+	))
+	if err != nil {
+		t.Fatalf("LoadBreachedPasswords() error = %v", err)
+	}
+
+	policy := PasswordPolicy{
+		MinLength:   8,
+		MaxLength:   16,
+		BannedWords: []string{"chirpy"},
+		Breached:    breached,
+	}
+
+	checkPasswordTests := []struct {
+		name     string
+		password string
+		hasRules []string
+	}{
+		{name: "Valid password", password: "correct horse", hasRules: nil},
+		{name: "Empty password", password: "", hasRules: []string{RuleMinLength}},
+		{name: "Too long", password: "correct horse battery staple", hasRules: []string{RuleMaxLength}},
+		{name: "Banned word", password: "MyChirpyPass", hasRules: []string{RuleContextWord}},
+		{name: "Email local part", password: "walt1234!", hasRules: []string{RuleContextWord}},
+		{name: "Breached", password: "password1", hasRules: []string{RuleBreached}},
+		{name: "Several rules", password: "chirpy", hasRules: []string{RuleMinLength, RuleContextWord}},
+	}
+
+	for _, tt := range checkPasswordTests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations := policy.CheckPassword(tt.password, EmailContextWords("walt@example.com")...)
+			var rules []string
+			for _, v := range violations {
+				rules = append(rules, v.Rule)
+			}
+			if strings.Join(rules, ",") != strings.Join(tt.hasRules, ",") {
+				t.Errorf("CheckPassword() rules = %v, want %v", rules, tt.hasRules)
+			}
+		})
+	}
+}
+
+func TestLoadBreachedPasswordsInvalid(t *testing.T) {
+	if _, err := LoadBreachedPasswords(strings.NewReader("not-a-hash\n")); err == nil {
+		t.Errorf("LoadBreachedPasswords() error = nil, want error")
+	}
+}
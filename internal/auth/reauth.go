@@ -0,0 +1,29 @@
+package auth
+
+import "errors"
+
+var (
+	ErrReauthDelegated       = errors.New("sign-in details can only be changed from a session")
+	ErrReauthPasswordMissing = errors.New("current password is required")
+	ErrReauthPasswordWrong   = errors.New("incorrect password")
+)
+
+// Reauthenticate checks that a caller changing what an account signs in
+// with, its password or email address, is the account's owner in person.
+// They must be using a session rather than a token acting on their behalf,
+// such as a personal access token or an OAuth client, and must give the
+// current password, which hashedPassword is the hash of.
+func Reauthenticate(delegated bool, currentPassword, hashedPassword string) error {
+	if delegated {
+		return ErrReauthDelegated
+	}
+	if currentPassword == "" {
+		return ErrReauthPasswordMissing
+	}
+
+	match, err := CheckPasswordHash(currentPassword, hashedPassword)
+	if err != nil || !match {
+		return ErrReauthPasswordWrong
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alexedwards/argon2id"
+)
+
+func TestReauthenticate(t *testing.T) {
+	params := &argon2id.Params{Memory: 8 * 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	hash, err := HashPassword("correct horse", params)
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		delegated       bool
+		currentPassword string
+		wantErr         error
+	}{
+		{
+			name:            "Session with the right password",
+			currentPassword: "correct horse",
+		},
+		{
+			name:            "Session with the wrong password",
+			currentPassword: "battery staple",
+			wantErr:         ErrReauthPasswordWrong,
+		},
+		{
+			name:    "Session without a password",
+			wantErr: ErrReauthPasswordMissing,
+		},
+		{
+			name:            "Personal access token with the right password",
+			delegated:       true,
+			currentPassword: "correct horse",
+			wantErr:         ErrReauthDelegated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Reauthenticate(tt.delegated, tt.currentPassword, hash)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Reauthenticate() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// encryptedSecretPrefix marks a secret encrypted by EncryptSecret, so it
+// can be told apart from one stored before encryption was introduced.
+const encryptedSecretPrefix = "enc:v1:"
+
+var ErrSecretCiphertext = errors.New("encrypted secret is malformed or was encrypted with another key")
+
+// ParseSecretKey decodes a base64 encoded 32 byte AES-256 key.
+func ParseSecretKey(value string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("secret key is not valid base64: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("secret key must be 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
+
+func secretAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptSecret encrypts a secret that has to be recovered later, such as
+// a TOTP seed, with AES-256-GCM. Unlike a hash, reading the database isn't
+// enough to recover it without key.
+func EncryptSecret(key []byte, plaintext string) (string, error) {
+	aead, err := secretAEAD(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedSecretPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptSecret reverses EncryptSecret.
+func DecryptSecret(key []byte, ciphertext string) (string, error) {
+	encoded, ok := strings.CutPrefix(ciphertext, encryptedSecretPrefix)
+	if !ok {
+		return "", ErrSecretCiphertext
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrSecretCiphertext
+	}
+
+	aead, err := secretAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", ErrSecretCiphertext
+	}
+
+	nonce, sealed := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", ErrSecretCiphertext
+	}
+	return string(plaintext), nil
+}
+
+// IsEncryptedSecret reports whether a stored secret was encrypted by
+// EncryptSecret.
+func IsEncryptedSecret(value string) bool {
+	return strings.HasPrefix(value, encryptedSecretPrefix)
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestEncryptSecret(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	otherKey := bytes.Repeat([]byte{2}, 32)
+
+	ciphertext, err := EncryptSecret(key, "JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatalf("EncryptSecret() error = %v", err)
+	}
+	if !IsEncryptedSecret(ciphertext) {
+		t.Errorf("IsEncryptedSecret(%q) = false, want true", ciphertext)
+	}
+	if IsEncryptedSecret("JBSWY3DPEHPK3PXP") {
+		t.Errorf("IsEncryptedSecret() of a plaintext secret = true, want false")
+	}
+
+	again, err := EncryptSecret(key, "JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatalf("EncryptSecret() error = %v", err)
+	}
+	if again == ciphertext {
+		t.Errorf("EncryptSecret() gave the same ciphertext twice")
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(ciphertext[len(encryptedSecretPrefix):])
+	if err != nil {
+		t.Fatalf("decoding ciphertext: %v", err)
+	}
+	sealed[len(sealed)-1] ^= 1
+	tampered := encryptedSecretPrefix + base64.RawStdEncoding.EncodeToString(sealed)
+
+	tests := []struct {
+		name       string
+		key        []byte
+		ciphertext string
+		want       string
+		wantErr    error
+	}{
+		{
+			name:       "Right key",
+			key:        key,
+			ciphertext: ciphertext,
+			want:       "JBSWY3DPEHPK3PXP",
+		},
+		{
+			name:       "Wrong key",
+			key:        otherKey,
+			ciphertext: ciphertext,
+			wantErr:    ErrSecretCiphertext,
+		},
+		{
+			name:       "Tampered ciphertext",
+			key:        key,
+			ciphertext: tampered,
+			wantErr:    ErrSecretCiphertext,
+		},
+		{
+			name:       "Plaintext",
+			key:        key,
+			ciphertext: "JBSWY3DPEHPK3PXP",
+			wantErr:    ErrSecretCiphertext,
+		},
+		{
+			name:       "Truncated",
+			key:        key,
+			ciphertext: encryptedSecretPrefix + "AAAA",
+			wantErr:    ErrSecretCiphertext,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecryptSecret(tt.key, tt.ciphertext)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DecryptSecret() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("DecryptSecret() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSecretKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{name: "32 bytes", value: base64.StdEncoding.EncodeToString(make([]byte, 32))},
+		{name: "16 bytes", value: base64.StdEncoding.EncodeToString(make([]byte, 16)), wantErr: true},
+		{name: "Not base64", value: "not base64!", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSecretKey(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseSecretKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
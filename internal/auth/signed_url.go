@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrSignedURLExpired   = errors.New("signed URL is missing its expiry or has expired")
+	ErrSignedURLSignature = errors.New("signed URL signature is missing or invalid")
+)
+
+// SignURL returns the query parameters that grant access to path until
+// expires, without any other credentials. The signature is an HMAC-SHA256
+// keyed with key over the path and expiry, so neither can be changed.
+func SignURL(key, path string, expires time.Time) url.Values {
+	return url.Values{
+		"expires":   {strconv.FormatInt(expires.Unix(), 10)},
+		"signature": {hex.EncodeToString(signedURLMAC(key, path, expires.Unix()))},
+	}
+}
+
+func signedURLMAC(key, path string, expires int64) []byte {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte("url:"))
+	mac.Write([]byte(path))
+	mac.Write([]byte("."))
+	mac.Write([]byte(strconv.FormatInt(expires, 10)))
+	return mac.Sum(nil)
+}
+
+// VerifySignedURL checks that query holds a signature from SignURL for
+// path that hasn't expired.
+func VerifySignedURL(key, path string, query url.Values, now time.Time) error {
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil || !now.Before(time.Unix(expires, 0)) {
+		return ErrSignedURLExpired
+	}
+
+	signature, err := hex.DecodeString(query.Get("signature"))
+	if err != nil || !hmac.Equal(signature, signedURLMAC(key, path, expires)) {
+		return ErrSignedURLSignature
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestVerifySignedURL(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	path := "/api/me/export/3311741c-680c-4546-99f3-fc9efac2036c"
+	query := SignURL("key", path, now.Add(15*time.Minute))
+
+	withParam := func(name, value string) url.Values {
+		q := url.Values{}
+		for k, v := range query {
+			q[k] = v
+		}
+		q.Set(name, value)
+		return q
+	}
+
+	tests := []struct {
+		name    string
+		key     string
+		path    string
+		query   url.Values
+		now     time.Time
+		wantErr error
+	}{
+		{
+			name:  "Valid link",
+			key:   "key",
+			path:  path,
+			query: query,
+			now:   now,
+		},
+		{
+			name:    "Expired link",
+			key:     "key",
+			path:    path,
+			query:   query,
+			now:     now.Add(15 * time.Minute),
+			wantErr: ErrSignedURLExpired,
+		},
+		{
+			name:    "Extended expiry",
+			key:     "key",
+			path:    path,
+			query:   withParam("expires", "1800000000"),
+			now:     now,
+			wantErr: ErrSignedURLSignature,
+		},
+		{
+			name:    "Other path",
+			key:     "key",
+			path:    "/api/me/export/00000000-0000-0000-0000-000000000000",
+			query:   query,
+			now:     now,
+			wantErr: ErrSignedURLSignature,
+		},
+		{
+			name:    "Wrong key",
+			key:     "other-key",
+			path:    path,
+			query:   query,
+			now:     now,
+			wantErr: ErrSignedURLSignature,
+		},
+		{
+			name:    "Missing signature",
+			key:     "key",
+			path:    path,
+			query:   withParam("signature", ""),
+			now:     now,
+			wantErr: ErrSignedURLSignature,
+		},
+		{
+			name:    "Missing expiry",
+			key:     "key",
+			path:    path,
+			query:   url.Values{"signature": query["signature"]},
+			now:     now,
+			wantErr: ErrSignedURLExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifySignedURL(tt.key, tt.path, tt.query, tt.now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifySignedURL() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
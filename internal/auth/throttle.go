@@ -0,0 +1,23 @@
+package auth
+
+import "time"
+
+// LoginBackoff returns how long logins should be locked after failures
+// consecutive failed attempts. The first freeAttempts failures aren't
+// penalised; after that the lockout starts at base and doubles with every
+// failure, up to max.
+func LoginBackoff(failures, freeAttempts int, base, max time.Duration) time.Duration {
+	if failures <= freeAttempts {
+		return 0
+	}
+
+	backoff := base
+	for i := freeAttempts + 1; i < failures; i++ {
+		backoff *= 2
+		if backoff >= max {
+			return max
+		}
+	}
+
+	return min(backoff, max)
+}
@@ -0,0 +1,29 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoginBackoff(t *testing.T) {
+	loginBackoffTests := []struct {
+		name       string
+		failures   int
+		hasBackoff time.Duration
+	}{
+		{name: "No failures", failures: 0, hasBackoff: 0},
+		{name: "Free attempts", failures: 5, hasBackoff: 0},
+		{name: "First lockout", failures: 6, hasBackoff: 30 * time.Second},
+		{name: "Doubled lockout", failures: 8, hasBackoff: 2 * time.Minute},
+		{name: "Capped lockout", failures: 40, hasBackoff: 15 * time.Minute},
+	}
+
+	for _, tt := range loginBackoffTests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := LoginBackoff(tt.failures, 5, 30*time.Second, 15*time.Minute)
+			if got != tt.hasBackoff {
+				t.Errorf("LoginBackoff() = %v, want %v", got, tt.hasBackoff)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// Test vectors from RFC 6238 appendix B, truncated to six digits.
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	totpCodeTests := []struct {
+		name    string
+		time    int64
+		hasCode string
+	}{
+		{name: "59", time: 59, hasCode: "287082"},
+		{name: "1111111109", time: 1111111109, hasCode: "081804"},
+		{name: "1234567890", time: 1234567890, hasCode: "005924"},
+		{name: "2000000000", time: 2000000000, hasCode: "279037"},
+	}
+
+	for _, tt := range totpCodeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotCode, err := TOTPCode(secret, TOTPStep(time.Unix(tt.time, 0)))
+			if err != nil {
+				t.Fatalf("TOTPCode() error = %v", err)
+			}
+			if gotCode != tt.hasCode {
+				t.Errorf("TOTPCode() = %q, want %q", gotCode, tt.hasCode)
+			}
+		})
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	validateTOTPTests := []struct {
+		name    string
+		code    string
+		now     int64
+		hasStep int64
+		hasOK   bool
+	}{
+		{name: "Current code", code: "287082", now: 59, hasStep: 1, hasOK: true},
+		{name: "Previous code", code: "287082", now: 89, hasStep: 1, hasOK: true},
+		{name: "Stale code", code: "287082", now: 149, hasStep: 0, hasOK: false},
+		{name: "Wrong code", code: "081804", now: 59, hasStep: 0, hasOK: false},
+		{name: "Malformed code", code: "28708", now: 59, hasStep: 0, hasOK: false},
+	}
+
+	for _, tt := range validateTOTPTests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, ok := ValidateTOTP(secret, tt.code, time.Unix(tt.now, 0))
+			if ok != tt.hasOK {
+				t.Errorf("ValidateTOTP() ok = %v, want %v", ok, tt.hasOK)
+			}
+			if gotStep != tt.hasStep {
+				t.Errorf("ValidateTOTP() step = %v, want %v", gotStep, tt.hasStep)
+			}
+		})
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(1)
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes() error = %v", err)
+	}
+
+	code := codes[0]
+	if got := NormalizeRecoveryCode(code); got != code {
+		t.Errorf("NormalizeRecoveryCode(%q) = %q, want unchanged", code, got)
+	}
+
+	typed := " " + code[:5] + code[6:] + " "
+	if got := NormalizeRecoveryCode(typed); got != code {
+		t.Errorf("NormalizeRecoveryCode(%q) = %q, want %q", typed, got, code)
+	}
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerifyWebhook(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"event":"user.upgraded"}`)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	oldSignature := SignWebhook("old-secret", now.Unix(), body)
+	newSignature := SignWebhook("new-secret", now.Unix(), body)
+
+	tests := []struct {
+		name      string
+		secrets   []string
+		timestamp string
+		signature string
+		body      []byte
+		wantErr   error
+	}{
+		{
+			name:      "Valid signature",
+			secrets:   []string{"new-secret"},
+			timestamp: timestamp,
+			signature: newSignature,
+			body:      body,
+		},
+		{
+			name:      "Rotated secret",
+			secrets:   []string{"new-secret", "old-secret"},
+			timestamp: timestamp,
+			signature: oldSignature,
+			body:      body,
+		},
+		{
+			name:      "Several signatures",
+			secrets:   []string{"new-secret"},
+			timestamp: timestamp,
+			signature: oldSignature + ", " + newSignature,
+			body:      body,
+		},
+		{
+			name:      "Wrong secret",
+			secrets:   []string{"other-secret"},
+			timestamp: timestamp,
+			signature: newSignature,
+			body:      body,
+			wantErr:   ErrWebhookSignature,
+		},
+		{
+			name:      "Tampered body",
+			secrets:   []string{"new-secret"},
+			timestamp: timestamp,
+			signature: newSignature,
+			body:      []byte(`{"event":"user.downgraded"}`),
+			wantErr:   ErrWebhookSignature,
+		},
+		{
+			name:      "Stale timestamp",
+			secrets:   []string{"new-secret"},
+			timestamp: strconv.FormatInt(now.Add(-10*time.Minute).Unix(), 10),
+			signature: SignWebhook("new-secret", now.Add(-10*time.Minute).Unix(), body),
+			body:      body,
+			wantErr:   ErrWebhookTimestamp,
+		},
+		{
+			name:      "Missing timestamp",
+			secrets:   []string{"new-secret"},
+			signature: newSignature,
+			body:      body,
+			wantErr:   ErrWebhookTimestamp,
+		},
+		{
+			name:      "Unknown version",
+			secrets:   []string{"new-secret"},
+			timestamp: timestamp,
+			signature: "v0" + newSignature[2:],
+			body:      body,
+			wantErr:   ErrWebhookSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyWebhook(tt.secrets, tt.timestamp, tt.signature, tt.body, 5*time.Minute, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyWebhook() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -53,9 +53,13 @@ func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
 
 const getChirp = `-- name: GetChirp :one
 SELECT
-  id, created_at, updated_at, body, user_id
+  chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id
 FROM chirps
-WHERE id = $1
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.id = $1
+  AND users.deletion_scheduled_for IS NULL
+  AND users.status <> 'banned'
+  AND NOT (users.status = 'suspended' AND users.suspended_until > NOW())
 `
 
 func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
@@ -73,9 +77,13 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 
 const getChirps = `-- name: GetChirps :many
 SELECT
-  id, created_at, updated_at, body, user_id
+  chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id
 FROM chirps
-ORDER BY created_at ASC
+JOIN users ON users.id = chirps.user_id
+WHERE users.deletion_scheduled_for IS NULL
+  AND users.status <> 'banned'
+  AND NOT (users.status = 'suspended' AND users.suspended_until > NOW())
+ORDER BY chirps.created_at ASC
 `
 
 func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
@@ -106,3 +114,38 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 	}
 	return items, nil
 }
+
+const getChirpsForUser = `-- name: GetChirpsForUser :many
+SELECT id, created_at, updated_at, body, user_id FROM chirps
+WHERE user_id = $1
+ORDER BY created_at ASC
+`
+
+func (q *Queries) GetChirpsForUser(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
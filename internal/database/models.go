@@ -6,11 +6,24 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type AuditEvent struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.NullUUID
+	EventType string
+	IpAddress string
+	UserAgent string
+	Details   json.RawMessage
+	ActorID   uuid.NullUUID
+	RequestID string
+}
+
 type Chirp struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
@@ -19,20 +32,177 @@ type Chirp struct {
 	UserID    uuid.UUID
 }
 
-type RefreshToken struct {
-	Token     string
+type DataExport struct {
+	ID           uuid.UUID
+	UserID       uuid.UUID
+	Status       string
+	Archive      []byte
+	Error        string
+	ClaimedUntil sql.NullTime
+	CreatedAt    time.Time
+	CompletedAt  sql.NullTime
+	ExpiresAt    sql.NullTime
+}
+
+type EmailVerificationToken struct {
+	TokenHash string
+	UserID    uuid.UUID
+	Email     string
 	CreatedAt time.Time
-	UpdatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type LoginThrottle struct {
+	ThrottleKey   string
+	Failures      int32
+	LastFailureAt time.Time
+	LockedUntil   sql.NullTime
+}
+
+type OauthAuthorizationCode struct {
+	CodeHash      string
+	ClientID      uuid.UUID
+	UserID        uuid.UUID
+	RedirectUri   string
+	Scopes        []string
+	CodeChallenge string
+	CreatedAt     time.Time
+	ExpiresAt     time.Time
+	UsedAt        sql.NullTime
+}
+
+type OauthClient struct {
+	ID           uuid.UUID
+	OwnerID      uuid.UUID
+	Name         string
+	SecretHash   sql.NullString
+	RedirectUris []string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+type PasswordResetToken struct {
+	TokenHash string
 	UserID    uuid.UUID
+	CreatedAt time.Time
 	ExpiresAt time.Time
-	RevokedAt sql.NullTime
+	UsedAt    sql.NullTime
+}
+
+type PersonalAccessToken struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Name       string
+	TokenHash  string
+	Scopes     []string
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	LastUsedAt sql.NullTime
+	RevokedAt  sql.NullTime
+}
+
+type RecoveryCode struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	CodeHash  string
+	CreatedAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type RefreshToken struct {
+	Token      sql.NullString
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	ExpiresAt  time.Time
+	RevokedAt  sql.NullTime
+	TokenHash  string
+	ID         uuid.UUID
+	LastUsedAt sql.NullTime
+	UserAgent  string
+	IpAddress  string
+	ClientID   uuid.NullUUID
+	Scopes     []string
+}
+
+type RevokedAccessToken struct {
+	Jti       string
+	UserID    uuid.UUID
+	RevokedAt time.Time
+	ExpiresAt time.Time
+}
+
+type Subscription struct {
+	ID                uuid.UUID
+	UserID            uuid.UUID
+	Plan              string
+	Status            string
+	CurrentPeriodEnd  sql.NullTime
+	CancelAtPeriodEnd bool
+	CanceledAt        sql.NullTime
+	GracePeriodEndsAt sql.NullTime
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
 }
 
 type User struct {
+	ID                   uuid.UUID
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+	Email                string
+	HashedPassword       string
+	IsChirpyRed          bool
+	TokensRevokedBefore  sql.NullTime
+	TotpSecret           sql.NullString
+	TotpEnabledAt        sql.NullTime
+	TotpLastStep         int64
+	EmailVerifiedAt      sql.NullTime
+	PendingEmail         sql.NullString
+	Role                 string
+	DeletionScheduledFor sql.NullTime
+	Status               string
+	SuspendedUntil       sql.NullTime
+	SuspensionReason     string
+}
+
+type WebhookDelivery struct {
 	ID             uuid.UUID
+	EndpointID     uuid.UUID
+	EventID        uuid.UUID
+	EventType      string
+	Payload        json.RawMessage
+	Status         string
+	Attempts       int32
+	NextAttemptAt  time.Time
+	LastAttemptAt  sql.NullTime
+	LastStatusCode sql.NullInt32
+	LastError      string
 	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	Email          string
-	HashedPassword string
-	IsChirpyRed    bool
+	DeliveredAt    sql.NullTime
+}
+
+type WebhookEndpoint struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	ClientID   uuid.NullUUID
+	Url        string
+	Secret     string
+	EventTypes []string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+type WebhookEvent struct {
+	ID           uuid.UUID
+	Provider     string
+	EventID      string
+	EventType    string
+	Payload      json.RawMessage
+	Status       string
+	Error        string
+	Attempts     int32
+	ClaimedUntil sql.NullTime
+	ReceivedAt   time.Time
+	ProcessedAt  sql.NullTime
 }
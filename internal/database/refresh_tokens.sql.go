@@ -7,29 +7,39 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createRefreshToken = `-- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at)
+INSERT INTO refresh_tokens (token_hash, created_at, updated_at, user_id, expires_at, user_agent, ip_address, client_id, scopes)
 VALUES (
   $1,
   NOW(),
   NOW(),
   $2,
-  NOW() + INTERVAL '60 days'
+  NOW() + INTERVAL '60 days',
+  $3,
+  $4,
+  $5,
+  $6
 )
-RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at
+RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at, token_hash, id, last_used_at, user_agent, ip_address, client_id, scopes
 `
 
 type CreateRefreshTokenParams struct {
-	Token  string
-	UserID uuid.UUID
+	TokenHash string
+	UserID    uuid.UUID
+	UserAgent string
+	IpAddress string
+	ClientID  uuid.NullUUID
+	Scopes    []string
 }
 
 func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshToken, error) {
-	row := q.db.QueryRowContext(ctx, createRefreshToken, arg.Token, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createRefreshToken, arg.TokenHash, arg.UserID, arg.UserAgent, arg.IpAddress, arg.ClientID, pq.Array(arg.Scopes))
 	var i RefreshToken
 	err := row.Scan(
 		&i.Token,
@@ -38,17 +48,26 @@ func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshToken
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.TokenHash,
+		&i.ID,
+		&i.LastUsedAt,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.ClientID,
+		pq.Array(&i.Scopes),
 	)
 	return i, err
 }
 
-const getUserFromRefreshToken = `-- name: GetUserFromRefreshToken :one
-SELECT token, created_at, updated_at, user_id, expires_at, revoked_at FROM refresh_tokens
-WHERE token = $1
+const getActiveRefreshToken = `-- name: GetActiveRefreshToken :one
+SELECT token, created_at, updated_at, user_id, expires_at, revoked_at, token_hash, id, last_used_at, user_agent, ip_address, client_id, scopes FROM refresh_tokens
+WHERE token_hash = $1
+  AND revoked_at IS NULL
+  AND expires_at > NOW()
 `
 
-func (q *Queries) GetUserFromRefreshToken(ctx context.Context, token string) (RefreshToken, error) {
-	row := q.db.QueryRowContext(ctx, getUserFromRefreshToken, token)
+func (q *Queries) GetActiveRefreshToken(ctx context.Context, tokenHash string) (RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, getActiveRefreshToken, tokenHash)
 	var i RefreshToken
 	err := row.Scan(
 		&i.Token,
@@ -57,18 +76,237 @@ func (q *Queries) GetUserFromRefreshToken(ctx context.Context, token string) (Re
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.TokenHash,
+		&i.ID,
+		&i.LastUsedAt,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.ClientID,
+		pq.Array(&i.Scopes),
 	)
 	return i, err
 }
 
+const getActiveRefreshTokensForUser = `-- name: GetActiveRefreshTokensForUser :many
+SELECT token, created_at, updated_at, user_id, expires_at, revoked_at, token_hash, id, last_used_at, user_agent, ip_address, client_id, scopes FROM refresh_tokens
+WHERE user_id = $1
+  AND revoked_at IS NULL
+  AND expires_at > NOW()
+ORDER BY created_at DESC
+`
+
+func (q *Queries) GetActiveRefreshTokensForUser(ctx context.Context, userID uuid.UUID) ([]RefreshToken, error) {
+	rows, err := q.db.QueryContext(ctx, getActiveRefreshTokensForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RefreshToken
+	for rows.Next() {
+		var i RefreshToken
+		if err := rows.Scan(
+			&i.Token,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.ExpiresAt,
+			&i.RevokedAt,
+			&i.TokenHash,
+			&i.ID,
+			&i.LastUsedAt,
+			&i.UserAgent,
+			&i.IpAddress,
+			&i.ClientID,
+			pq.Array(&i.Scopes),
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLegacyRefreshTokens = `-- name: GetLegacyRefreshTokens :many
+SELECT token, created_at, updated_at, user_id, expires_at, revoked_at, token_hash, id, last_used_at, user_agent, ip_address, client_id, scopes FROM refresh_tokens
+WHERE token IS NOT NULL
+`
+
+func (q *Queries) GetLegacyRefreshTokens(ctx context.Context) ([]RefreshToken, error) {
+	rows, err := q.db.QueryContext(ctx, getLegacyRefreshTokens)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RefreshToken
+	for rows.Next() {
+		var i RefreshToken
+		if err := rows.Scan(
+			&i.Token,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.ExpiresAt,
+			&i.RevokedAt,
+			&i.TokenHash,
+			&i.ID,
+			&i.LastUsedAt,
+			&i.UserAgent,
+			&i.IpAddress,
+			&i.ClientID,
+			pq.Array(&i.Scopes),
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRefreshTokensForUser = `-- name: GetRefreshTokensForUser :many
+SELECT token, created_at, updated_at, user_id, expires_at, revoked_at, token_hash, id, last_used_at, user_agent, ip_address, client_id, scopes FROM refresh_tokens
+WHERE user_id = $1
+ORDER BY created_at DESC
+`
+
+func (q *Queries) GetRefreshTokensForUser(ctx context.Context, userID uuid.UUID) ([]RefreshToken, error) {
+	rows, err := q.db.QueryContext(ctx, getRefreshTokensForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RefreshToken
+	for rows.Next() {
+		var i RefreshToken
+		if err := rows.Scan(
+			&i.Token,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.ExpiresAt,
+			&i.RevokedAt,
+			&i.TokenHash,
+			&i.ID,
+			&i.LastUsedAt,
+			&i.UserAgent,
+			&i.IpAddress,
+			&i.ClientID,
+			pq.Array(&i.Scopes),
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const hashLegacyRefreshToken = `-- name: HashLegacyRefreshToken :exec
+UPDATE refresh_tokens
+SET token_hash = $1,
+    token = NULL
+WHERE token = $2
+`
+
+type HashLegacyRefreshTokenParams struct {
+	TokenHash string
+	Token     sql.NullString
+}
+
+func (q *Queries) HashLegacyRefreshToken(ctx context.Context, arg HashLegacyRefreshTokenParams) error {
+	_, err := q.db.ExecContext(ctx, hashLegacyRefreshToken, arg.TokenHash, arg.Token)
+	return err
+}
+
+const revokeAllRefreshTokens = `-- name: RevokeAllRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE user_id = $1
+  AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeAllRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeAllRefreshTokens, userID)
+	return err
+}
+
+const revokeOtherRefreshTokens = `-- name: RevokeOtherRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE user_id = $1
+  AND id <> $2
+  AND revoked_at IS NULL
+`
+
+type RevokeOtherRefreshTokensParams struct {
+	UserID uuid.UUID
+	ID     uuid.UUID
+}
+
+func (q *Queries) RevokeOtherRefreshTokens(ctx context.Context, arg RevokeOtherRefreshTokensParams) error {
+	_, err := q.db.ExecContext(ctx, revokeOtherRefreshTokens, arg.UserID, arg.ID)
+	return err
+}
+
 const revokeRefreshToken = `-- name: RevokeRefreshToken :exec
 UPDATE refresh_tokens
 SET revoked_at = NOW(),
     updated_at = NOW()
-WHERE token = $1
+WHERE token_hash = $1
+`
+
+func (q *Queries) RevokeRefreshToken(ctx context.Context, tokenHash string) error {
+	_, err := q.db.ExecContext(ctx, revokeRefreshToken, tokenHash)
+	return err
+}
+
+const revokeRefreshTokenByID = `-- name: RevokeRefreshTokenByID :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+  AND user_id = $2
+  AND revoked_at IS NULL
+`
+
+type RevokeRefreshTokenByIDParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) RevokeRefreshTokenByID(ctx context.Context, arg RevokeRefreshTokenByIDParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeRefreshTokenByID, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const touchRefreshToken = `-- name: TouchRefreshToken :exec
+UPDATE refresh_tokens
+SET last_used_at = NOW()
+WHERE id = $1
 `
 
-func (q *Queries) RevokeRefreshToken(ctx context.Context, token string) error {
-	_, err := q.db.ExecContext(ctx, revokeRefreshToken, token)
+func (q *Queries) TouchRefreshToken(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, touchRefreshToken, id)
 	return err
 }
@@ -7,10 +7,80 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+const banUser = `-- name: BanUser :one
+UPDATE users
+SET status = 'banned',
+    suspended_until = NULL,
+    suspension_reason = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, tokens_revoked_before, totp_secret, totp_enabled_at, totp_last_step, email_verified_at, pending_email, role, deletion_scheduled_for, status, suspended_until, suspension_reason
+`
+
+type BanUserParams struct {
+	ID               uuid.UUID
+	SuspensionReason string
+}
+
+func (q *Queries) BanUser(ctx context.Context, arg BanUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, banUser, arg.ID, arg.SuspensionReason)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.TokensRevokedBefore,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.Role,
+		&i.DeletionScheduledFor,
+		&i.Status,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+	)
+	return i, err
+}
+
+const cancelUserDeletion = `-- name: CancelUserDeletion :execrows
+UPDATE users
+SET deletion_scheduled_for = NULL,
+    updated_at = NOW()
+WHERE id = $1
+  AND deletion_scheduled_for IS NOT NULL
+`
+
+func (q *Queries) CancelUserDeletion(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, cancelUserDeletion, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const countUsersWithRole = `-- name: CountUsersWithRole :one
+SELECT COUNT(*) FROM users
+WHERE role = $1
+`
+
+func (q *Queries) CountUsersWithRole(ctx context.Context, role string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUsersWithRole, role)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (id, created_at, updated_at, email, hashed_password)
 VALUES (
@@ -20,7 +90,7 @@ VALUES (
   $1,
   $2
 )
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, tokens_revoked_before, totp_secret, totp_enabled_at, totp_last_step, email_verified_at, pending_email, role, deletion_scheduled_for, status, suspended_until, suspension_reason
 `
 
 type CreateUserParams struct {
@@ -38,6 +108,17 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.TokensRevokedBefore,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.Role,
+		&i.DeletionScheduledFor,
+		&i.Status,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
 	)
 	return i, err
 }
@@ -51,8 +132,169 @@ func (q *Queries) DeleteUsers(ctx context.Context) error {
 	return err
 }
 
+const deleteUsersScheduledForDeletion = `-- name: DeleteUsersScheduledForDeletion :many
+DELETE FROM users
+WHERE deletion_scheduled_for <= NOW()
+RETURNING id
+`
+
+func (q *Queries) DeleteUsersScheduledForDeletion(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, deleteUsersScheduledForDeletion)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const disableUserTOTP = `-- name: DisableUserTOTP :exec
+UPDATE users
+SET totp_secret = NULL,
+    totp_enabled_at = NULL,
+    totp_last_step = 0
+WHERE id = $1
+`
+
+func (q *Queries) DisableUserTOTP(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, disableUserTOTP, id)
+	return err
+}
+
+const downgradeUserFromChirpyRed = `-- name: DowngradeUserFromChirpyRed :exec
+UPDATE users
+SET is_chirpy_red = false
+WHERE id = $1
+`
+
+func (q *Queries) DowngradeUserFromChirpyRed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, downgradeUserFromChirpyRed, id)
+	return err
+}
+
+const enableUserTOTP = `-- name: EnableUserTOTP :exec
+UPDATE users
+SET totp_enabled_at = NOW(),
+    totp_last_step = $2
+WHERE id = $1
+`
+
+type EnableUserTOTPParams struct {
+	ID           uuid.UUID
+	TotpLastStep int64
+}
+
+func (q *Queries) EnableUserTOTP(ctx context.Context, arg EnableUserTOTPParams) error {
+	_, err := q.db.ExecContext(ctx, enableUserTOTP, arg.ID, arg.TotpLastStep)
+	return err
+}
+
+const encryptUserTOTPSecret = `-- name: EncryptUserTOTPSecret :exec
+UPDATE users
+SET totp_secret = $1::text
+WHERE id = $2
+  AND totp_secret = $3::text
+`
+
+type EncryptUserTOTPSecretParams struct {
+	EncryptedSecret string
+	ID              uuid.UUID
+	PlaintextSecret string
+}
+
+func (q *Queries) EncryptUserTOTPSecret(ctx context.Context, arg EncryptUserTOTPSecretParams) error {
+	_, err := q.db.ExecContext(ctx, encryptUserTOTPSecret, arg.EncryptedSecret, arg.ID, arg.PlaintextSecret)
+	return err
+}
+
+const getPlaintextTOTPSecrets = `-- name: GetPlaintextTOTPSecrets :many
+SELECT id, totp_secret::text FROM users
+WHERE totp_secret IS NOT NULL
+  AND totp_secret NOT LIKE 'enc:%'
+`
+
+type GetPlaintextTOTPSecretsRow struct {
+	ID         uuid.UUID
+	TotpSecret string
+}
+
+func (q *Queries) GetPlaintextTOTPSecrets(ctx context.Context) ([]GetPlaintextTOTPSecretsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPlaintextTOTPSecrets)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPlaintextTOTPSecretsRow
+	for rows.Next() {
+		var i GetPlaintextTOTPSecretsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.TotpSecret,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserActivity = `-- name: GetUserActivity :one
+SELECT
+  (SELECT COUNT(*) FROM chirps WHERE chirps.user_id = $1) AS chirps,
+  (SELECT COUNT(*) FROM refresh_tokens
+   WHERE refresh_tokens.user_id = $1
+     AND revoked_at IS NULL
+     AND expires_at > NOW()) AS active_sessions,
+  (SELECT COUNT(*) FROM personal_access_tokens
+   WHERE personal_access_tokens.user_id = $1
+     AND revoked_at IS NULL
+     AND expires_at > NOW()) AS personal_access_tokens,
+  (SELECT COUNT(*) FROM webhook_endpoints WHERE webhook_endpoints.user_id = $1) AS webhook_endpoints,
+  (SELECT MAX(created_at) FROM refresh_tokens WHERE refresh_tokens.user_id = $1)::timestamp AS last_login_at
+`
+
+type GetUserActivityRow struct {
+	Chirps               int64
+	ActiveSessions       int64
+	PersonalAccessTokens int64
+	WebhookEndpoints     int64
+	LastLoginAt          sql.NullTime
+}
+
+func (q *Queries) GetUserActivity(ctx context.Context, userID uuid.UUID) (GetUserActivityRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserActivity, userID)
+	var i GetUserActivityRow
+	err := row.Scan(
+		&i.Chirps,
+		&i.ActiveSessions,
+		&i.PersonalAccessTokens,
+		&i.WebhookEndpoints,
+		&i.LastLoginAt,
+	)
+	return i, err
+}
+
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red FROM users
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, tokens_revoked_before, totp_secret, totp_enabled_at, totp_last_step, email_verified_at, pending_email, role, deletion_scheduled_for, status, suspended_until, suspension_reason FROM users
 WHERE email = $1
 `
 
@@ -66,25 +308,315 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.TokensRevokedBefore,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.Role,
+		&i.DeletionScheduledFor,
+		&i.Status,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
 	)
 	return i, err
 }
 
-const updateUser = `-- name: UpdateUser :exec
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, tokens_revoked_before, totp_secret, totp_enabled_at, totp_last_step, email_verified_at, pending_email, role, deletion_scheduled_for, status, suspended_until, suspension_reason FROM users
+WHERE id = $1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.TokensRevokedBefore,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.Role,
+		&i.DeletionScheduledFor,
+		&i.Status,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+	)
+	return i, err
+}
+
+const lockUsersWithRole = `-- name: LockUsersWithRole :many
+SELECT id FROM users
+WHERE role = $1
+FOR UPDATE
+`
+
+func (q *Queries) LockUsersWithRole(ctx context.Context, role string) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, lockUsersWithRole, role)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeUserAccessTokens = `-- name: RevokeUserAccessTokens :exec
 UPDATE users
-SET email = $1,
-    hashed_password = $2
-WHERE id = $3
+SET tokens_revoked_before = $1::timestamp
+WHERE id = $2
 `
 
-type UpdateUserParams struct {
-	Email          string
-	HashedPassword string
+type RevokeUserAccessTokensParams struct {
+	TokensRevokedBefore time.Time
+	ID                  uuid.UUID
+}
+
+func (q *Queries) RevokeUserAccessTokens(ctx context.Context, arg RevokeUserAccessTokensParams) error {
+	_, err := q.db.ExecContext(ctx, revokeUserAccessTokens, arg.TokensRevokedBefore, arg.ID)
+	return err
+}
+
+const scheduleUserDeletion = `-- name: ScheduleUserDeletion :one
+UPDATE users
+SET deletion_scheduled_for = NOW() + make_interval(days => $1::int),
+    updated_at = NOW()
+WHERE id = $2
+RETURNING deletion_scheduled_for::timestamp
+`
+
+type ScheduleUserDeletionParams struct {
+	GracePeriodDays int32
+	ID              uuid.UUID
+}
+
+func (q *Queries) ScheduleUserDeletion(ctx context.Context, arg ScheduleUserDeletionParams) (time.Time, error) {
+	row := q.db.QueryRowContext(ctx, scheduleUserDeletion, arg.GracePeriodDays, arg.ID)
+	var deletionScheduledFor time.Time
+	err := row.Scan(&deletionScheduledFor)
+	return deletionScheduledFor, err
+}
+
+const searchUsers = `-- name: SearchUsers :many
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, tokens_revoked_before, totp_secret, totp_enabled_at, totp_last_step, email_verified_at, pending_email, role, deletion_scheduled_for, status, suspended_until, suspension_reason FROM users
+WHERE ($1::uuid IS NULL OR id = $1)
+  AND ($2::text IS NULL OR email ILIKE '%' || $2 || '%')
+  AND ($3::text IS NULL OR status = $3)
+ORDER BY created_at DESC
+LIMIT $4
+`
+
+type SearchUsersParams struct {
+	ID         uuid.NullUUID
+	Email      sql.NullString
+	Status     sql.NullString
+	MaxResults int32
+}
+
+func (q *Queries) SearchUsers(ctx context.Context, arg SearchUsersParams) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, searchUsers, arg.ID, arg.Email, arg.Status, arg.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.HashedPassword,
+			&i.IsChirpyRed,
+			&i.TokensRevokedBefore,
+			&i.TotpSecret,
+			&i.TotpEnabledAt,
+			&i.TotpLastStep,
+			&i.EmailVerifiedAt,
+			&i.PendingEmail,
+			&i.Role,
+			&i.DeletionScheduledFor,
+			&i.Status,
+			&i.SuspendedUntil,
+			&i.SuspensionReason,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setUserPendingEmail = `-- name: SetUserPendingEmail :exec
+UPDATE users
+SET pending_email = $1::text,
+    updated_at = NOW()
+WHERE id = $2
+`
+
+type SetUserPendingEmailParams struct {
+	PendingEmail string
+	ID           uuid.UUID
+}
+
+func (q *Queries) SetUserPendingEmail(ctx context.Context, arg SetUserPendingEmailParams) error {
+	_, err := q.db.ExecContext(ctx, setUserPendingEmail, arg.PendingEmail, arg.ID)
+	return err
+}
+
+const setUserRole = `-- name: SetUserRole :exec
+UPDATE users
+SET role = $2,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type SetUserRoleParams struct {
+	ID   uuid.UUID
+	Role string
+}
+
+func (q *Queries) SetUserRole(ctx context.Context, arg SetUserRoleParams) error {
+	_, err := q.db.ExecContext(ctx, setUserRole, arg.ID, arg.Role)
+	return err
+}
+
+const setUserTOTPSecret = `-- name: SetUserTOTPSecret :exec
+UPDATE users
+SET totp_secret = $1::text,
+    totp_enabled_at = NULL,
+    totp_last_step = 0
+WHERE id = $2
+`
+
+type SetUserTOTPSecretParams struct {
+	TotpSecret string
+	ID         uuid.UUID
+}
+
+func (q *Queries) SetUserTOTPSecret(ctx context.Context, arg SetUserTOTPSecretParams) error {
+	_, err := q.db.ExecContext(ctx, setUserTOTPSecret, arg.TotpSecret, arg.ID)
+	return err
+}
+
+const suspendUser = `-- name: SuspendUser :one
+UPDATE users
+SET status = 'suspended',
+    suspended_until = $2,
+    suspension_reason = $3,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, tokens_revoked_before, totp_secret, totp_enabled_at, totp_last_step, email_verified_at, pending_email, role, deletion_scheduled_for, status, suspended_until, suspension_reason
+`
+
+type SuspendUserParams struct {
+	ID               uuid.UUID
+	SuspendedUntil   sql.NullTime
+	SuspensionReason string
+}
+
+func (q *Queries) SuspendUser(ctx context.Context, arg SuspendUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, suspendUser, arg.ID, arg.SuspendedUntil, arg.SuspensionReason)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.TokensRevokedBefore,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.Role,
+		&i.DeletionScheduledFor,
+		&i.Status,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+	)
+	return i, err
+}
+
+const unsuspendUser = `-- name: UnsuspendUser :one
+UPDATE users
+SET status = 'active',
+    suspended_until = NULL,
+    suspension_reason = '',
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, tokens_revoked_before, totp_secret, totp_enabled_at, totp_last_step, email_verified_at, pending_email, role, deletion_scheduled_for, status, suspended_until, suspension_reason
+`
+
+func (q *Queries) UnsuspendUser(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, unsuspendUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.TokensRevokedBefore,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.Role,
+		&i.DeletionScheduledFor,
+		&i.Status,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+	)
+	return i, err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users
+SET hashed_password = $2,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateUserPasswordParams struct {
 	ID             uuid.UUID
+	HashedPassword string
 }
 
-func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) error {
-	_, err := q.db.ExecContext(ctx, updateUser, arg.Email, arg.HashedPassword, arg.ID)
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.ID, arg.HashedPassword)
 	return err
 }
 
@@ -98,3 +630,64 @@ func (q *Queries) UpgradeUserToChirpyRed(ctx context.Context, id uuid.UUID) erro
 	_, err := q.db.ExecContext(ctx, upgradeUserToChirpyRed, id)
 	return err
 }
+
+const useUserTOTPStep = `-- name: UseUserTOTPStep :execrows
+UPDATE users
+SET totp_last_step = $2
+WHERE id = $1
+  AND totp_last_step < $2
+`
+
+type UseUserTOTPStepParams struct {
+	ID           uuid.UUID
+	TotpLastStep int64
+}
+
+func (q *Queries) UseUserTOTPStep(ctx context.Context, arg UseUserTOTPStepParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, useUserTOTPStep, arg.ID, arg.TotpLastStep)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const verifyUserEmail = `-- name: VerifyUserEmail :one
+UPDATE users
+SET email = $1,
+    email_verified_at = NOW(),
+    pending_email = CASE WHEN pending_email = $1 THEN NULL ELSE pending_email END,
+    updated_at = NOW()
+WHERE id = $2
+  AND (email = $1 OR pending_email = $1)
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, tokens_revoked_before, totp_secret, totp_enabled_at, totp_last_step, email_verified_at, pending_email, role, deletion_scheduled_for, status, suspended_until, suspension_reason
+`
+
+type VerifyUserEmailParams struct {
+	Email string
+	ID    uuid.UUID
+}
+
+func (q *Queries) VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, verifyUserEmail, arg.Email, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.TokensRevokedBefore,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.Role,
+		&i.DeletionScheduledFor,
+		&i.Status,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+	)
+	return i, err
+}
@@ -0,0 +1,84 @@
+// Package export writes the archives users download their data in.
+package export
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+)
+
+// Archive is a ZIP archive of JSON and CSV files.
+type Archive struct {
+	zw       *zip.Writer
+	modified time.Time
+}
+
+// NewArchive returns an archive written to w. Every file in it is dated
+// modified.
+func NewArchive(w io.Writer, modified time.Time) *Archive {
+	return &Archive{
+		zw:       zip.NewWriter(w),
+		modified: modified,
+	}
+}
+
+func (a *Archive) create(name string) (io.Writer, error) {
+	return a.zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: a.modified,
+	})
+}
+
+// AddJSON adds a file holding v as indented JSON.
+func (a *Archive) AddJSON(name string, v any) error {
+	f, err := a.create(name)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// AddCSV adds a file holding a header row followed by rows.
+func (a *Archive) AddCSV(name string, header []string, rows [][]string) error {
+	f, err := a.create(name)
+	if err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(f)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		escaped := make([]string, len(row))
+		for i, cell := range row {
+			escaped[i] = escapeFormula(cell)
+		}
+		if err := cw.Write(escaped); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// escapeFormula stops a spreadsheet opening the CSV from evaluating a cell
+// as a formula, since cells like chirp bodies are user controlled.
+func escapeFormula(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// Close finishes the archive. It doesn't close the underlying writer.
+func (a *Archive) Close() error {
+	return a.zw.Close()
+}
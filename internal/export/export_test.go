@@ -0,0 +1,103 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"slices"
+	"testing"
+	"time"
+)
+
+func readArchive(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("reading archive: %v", err)
+	}
+
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("opening %s: %v", f.Name, err)
+		}
+		contents, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("reading %s: %v", f.Name, err)
+		}
+		files[f.Name] = contents
+	}
+	return files
+}
+
+func TestArchive(t *testing.T) {
+	var buf bytes.Buffer
+	a := NewArchive(&buf, time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	if err := a.AddJSON("profile.json", map[string]string{"email": "walt@example.com"}); err != nil {
+		t.Fatalf("AddJSON: %v", err)
+	}
+	rows := [][]string{{"1", "hello, world"}, {"2", "line\nbreak"}}
+	if err := a.AddCSV("chirps.csv", []string{"id", "body"}, rows); err != nil {
+		t.Fatalf("AddCSV: %v", err)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	files := readArchive(t, buf.Bytes())
+	if len(files) != 2 {
+		t.Fatalf("archive has %d files, want 2", len(files))
+	}
+
+	var profile map[string]string
+	if err := json.Unmarshal(files["profile.json"], &profile); err != nil {
+		t.Fatalf("decoding profile.json: %v", err)
+	}
+	if profile["email"] != "walt@example.com" {
+		t.Errorf("profile.json email = %q, want %q", profile["email"], "walt@example.com")
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(files["chirps.csv"])).ReadAll()
+	if err != nil {
+		t.Fatalf("decoding chirps.csv: %v", err)
+	}
+	want := [][]string{{"id", "body"}, {"1", "hello, world"}, {"2", "line\nbreak"}}
+	if len(records) != len(want) {
+		t.Fatalf("chirps.csv has %d records, want %d", len(records), len(want))
+	}
+	for i := range want {
+		if !slices.Equal(records[i], want[i]) {
+			t.Errorf("chirps.csv record %d = %q, want %q", i, records[i], want[i])
+		}
+	}
+}
+
+func TestEscapeFormula(t *testing.T) {
+	tests := []struct {
+		cell string
+		want string
+	}{
+		{cell: "", want: ""},
+		{cell: "hello", want: "hello"},
+		{cell: "=HYPERLINK(\"http://evil\")", want: "'=HYPERLINK(\"http://evil\")"},
+		{cell: "+1", want: "'+1"},
+		{cell: "-1", want: "'-1"},
+		{cell: "@SUM(A1)", want: "'@SUM(A1)"},
+		{cell: "\tcell", want: "'\tcell"},
+		{cell: "a=b", want: "a=b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cell, func(t *testing.T) {
+			if got := escapeFormula(tt.cell); got != tt.want {
+				t.Errorf("escapeFormula(%q) = %q, want %q", tt.cell, got, tt.want)
+			}
+		})
+	}
+}
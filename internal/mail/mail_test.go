@@ -0,0 +1,73 @@
+package mail
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestOutbox(t *testing.T) {
+	outboxTests := []struct {
+		name   string
+		msg    Message
+		hasErr bool
+	}{
+		{
+			name:   "Valid message",
+			msg:    Message{To: "user@example.com", Subject: "Hello", Body: "Hi there"},
+			hasErr: false,
+		},
+		{
+			name:   "Missing recipient",
+			msg:    Message{Subject: "Hello", Body: "Hi there"},
+			hasErr: true,
+		},
+		{
+			name:   "Header injection",
+			msg:    Message{To: "user@example.com", Subject: "Hello\r\nBcc: victim@example.com", Body: "Hi there"},
+			hasErr: true,
+		},
+	}
+
+	for _, tt := range outboxTests {
+		t.Run(tt.name, func(t *testing.T) {
+			outbox := &Outbox{}
+			err := outbox.Send(context.Background(), tt.msg)
+			if (err != nil) != tt.hasErr {
+				t.Errorf("Send() error = %v, wantErr %v", err, tt.hasErr)
+			}
+
+			wantMessages := 1
+			if tt.hasErr {
+				wantMessages = 0
+			}
+			if got := len(outbox.Messages()); got != wantMessages {
+				t.Errorf("Messages() returned %d messages, want %d", got, wantMessages)
+			}
+		})
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	mailer, err := NewFileMailer(dir, "chirpy@example.com")
+	if err != nil {
+		t.Fatalf("NewFileMailer() error = %v", err)
+	}
+
+	msg := Message{To: "user@example.com", Subject: "Hello", Body: "Hi there"}
+	if err := mailer.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("Send() wrote %d files, want 1", len(entries))
+	}
+
+	data, _ := os.ReadFile(dir + "/" + entries[0].Name())
+	if !strings.Contains(string(data), "To: user@example.com\r\n") {
+		t.Errorf("Send() wrote %q, want a To header", data)
+	}
+}
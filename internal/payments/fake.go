@@ -0,0 +1,112 @@
+package payments
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/M-Sviridov/chirpy/internal/auth"
+	"github.com/google/uuid"
+)
+
+const (
+	fakeTimestampHeader = "X-Fake-Payments-Timestamp"
+	fakeSignatureHeader = "X-Fake-Payments-Signature"
+	fakeCustomerPrefix  = "cus_"
+)
+
+// Fake is a stand-in payment provider for local end-to-end tests. Its
+// events are already in Chirpy's form, and its customer references are
+// user IDs with a prefix, so tests exercise the customer mapping too. Use
+// NewWebhookRequest to send it a delivery.
+type Fake struct {
+	Secret string
+}
+
+type fakeEvent struct {
+	ID               string     `json:"id"`
+	Type             string     `json:"type"`
+	Customer         string     `json:"customer"`
+	Plan             string     `json:"plan,omitempty"`
+	CurrentPeriodEnd *time.Time `json:"current_period_end,omitempty"`
+}
+
+func (f *Fake) Name() string {
+	return "fake"
+}
+
+func (f *Fake) VerifyWebhook(header http.Header, body []byte) error {
+	return auth.VerifyWebhook(
+		[]string{f.Secret},
+		header.Get(fakeTimestampHeader),
+		header.Get(fakeSignatureHeader),
+		body,
+		5*time.Minute,
+		time.Now(),
+	)
+}
+
+func (f *Fake) ParseEvent(body []byte) (Event, error) {
+	var params fakeEvent
+	if err := json.Unmarshal(body, &params); err != nil {
+		return Event{}, fmt.Errorf("couldn't decode fake event: %w", err)
+	}
+
+	event := Event{
+		ID:           params.ID,
+		Type:         params.Type,
+		ProviderType: params.Type,
+		CustomerID:   params.Customer,
+		Plan:         params.Plan,
+	}
+	if params.CurrentPeriodEnd != nil {
+		event.CurrentPeriodEnd = *params.CurrentPeriodEnd
+	}
+	return event, nil
+}
+
+func (f *Fake) UserID(customerID string) (uuid.UUID, error) {
+	id, ok := strings.CutPrefix(customerID, fakeCustomerPrefix)
+	if !ok {
+		return uuid.Nil, fmt.Errorf("invalid customer %q", customerID)
+	}
+	return uuid.Parse(id)
+}
+
+// CustomerID returns the fake provider's reference for a user.
+func (f *Fake) CustomerID(userID uuid.UUID) string {
+	return fakeCustomerPrefix + userID.String()
+}
+
+// NewWebhookRequest returns a signed delivery of event to url, as the
+// provider would send it.
+func (f *Fake) NewWebhookRequest(url string, event Event) (*http.Request, error) {
+	params := fakeEvent{
+		ID:       event.ID,
+		Type:     event.Type,
+		Customer: event.CustomerID,
+		Plan:     event.Plan,
+	}
+	if !event.CurrentPeriodEnd.IsZero() {
+		params.CurrentPeriodEnd = &event.CurrentPeriodEnd
+	}
+	body, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(fakeTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(fakeSignatureHeader, auth.SignWebhook(f.Secret, timestamp, body))
+	return req, nil
+}
@@ -0,0 +1,62 @@
+package payments
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event types, the same whichever provider an event came from.
+const (
+	EventSubscriptionActivated = "subscription.activated"
+	EventSubscriptionRenewed   = "subscription.renewed"
+	EventSubscriptionCanceled  = "subscription.canceled"
+	EventPaymentFailed         = "payment.failed"
+	EventPaymentRefunded       = "payment.refunded"
+)
+
+// Event is a provider's webhook event in a provider-independent form.
+type Event struct {
+	// ID is the provider's ID for the event, the same on every delivery,
+	// or empty if the provider didn't send one.
+	ID string
+	// Type is one of the Event constants, or empty if the event is of no
+	// interest to Chirpy.
+	Type string
+	// ProviderType is the event type as the provider named it.
+	ProviderType string
+	// CustomerID is the provider's reference for the customer the event
+	// concerns.
+	CustomerID string
+	Plan       string
+	// CurrentPeriodEnd is when the subscription's paid period ends, or
+	// zero if the provider didn't say.
+	CurrentPeriodEnd time.Time
+}
+
+// Provider adapts a payment provider's webhooks.
+type Provider interface {
+	// Name identifies the provider in webhook URLs and the event ledger.
+	Name() string
+	// VerifyWebhook checks that a delivery with the given headers and raw
+	// body was sent by the provider.
+	VerifyWebhook(header http.Header, body []byte) error
+	// ParseEvent normalizes the body of a delivery.
+	ParseEvent(body []byte) (Event, error)
+	// UserID maps one of the provider's customer references to the Chirpy
+	// user it belongs to.
+	UserID(customerID string) (uuid.UUID, error)
+}
+
+// LedgerID returns the key the event is recorded under in the webhook
+// ledger, where deliveries with the same key are only processed once. An
+// event without an ID gets a new key on every delivery: its body can't
+// tell a retry from a genuine repeat, such as a second renewal, so it's
+// processed every time rather than risk dropping one.
+func (e Event) LedgerID() string {
+	if e.ID != "" {
+		return e.ID
+	}
+	return "delivery:" + uuid.NewString()
+}
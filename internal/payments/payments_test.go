@@ -0,0 +1,172 @@
+package payments
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/M-Sviridov/chirpy/internal/auth"
+	"github.com/google/uuid"
+)
+
+func TestPolkaParseEvent(t *testing.T) {
+	userID := uuid.New()
+	periodEnd := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		body     string
+		wantID   string
+		wantType string
+	}{
+		{
+			name:     "Upgrade",
+			body:     `{"id":"evt_1","event":"user.upgraded","data":{"user_id":"` + userID.String() + `"}}`,
+			wantID:   "evt_1",
+			wantType: EventSubscriptionActivated,
+		},
+		{
+			name:     "Renewal",
+			body:     `{"id":"evt_2","event":"subscription.renewed","data":{"user_id":"` + userID.String() + `","current_period_end":"2030-01-02T03:04:05Z"}}`,
+			wantID:   "evt_2",
+			wantType: EventSubscriptionRenewed,
+		},
+		{
+			name:     "Unknown event",
+			body:     `{"id":"evt_3","event":"user.created","data":{"user_id":"` + userID.String() + `"}}`,
+			wantID:   "evt_3",
+			wantType: "",
+		},
+		{
+			name:     "Missing event ID",
+			body:     `{"event":"user.upgraded","data":{"user_id":"` + userID.String() + `"}}`,
+			wantType: EventSubscriptionActivated,
+		},
+	}
+
+	polka := &Polka{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := polka.ParseEvent([]byte(tt.body))
+			if err != nil {
+				t.Fatalf("ParseEvent() error = %v", err)
+			}
+			if event.Type != tt.wantType {
+				t.Errorf("ParseEvent() type = %q, want %q", event.Type, tt.wantType)
+			}
+			if event.ID != tt.wantID {
+				t.Errorf("ParseEvent() ID = %q, want %q", event.ID, tt.wantID)
+			}
+			if tt.wantType == EventSubscriptionRenewed && !event.CurrentPeriodEnd.Equal(periodEnd) {
+				t.Errorf("ParseEvent() period end = %v, want %v", event.CurrentPeriodEnd, periodEnd)
+			}
+			if got, err := polka.UserID(event.CustomerID); err != nil || got != userID {
+				t.Errorf("UserID() = %v, %v, want %v", got, err, userID)
+			}
+		})
+	}
+}
+
+func TestEventLedgerID(t *testing.T) {
+	userID := uuid.New()
+	renewal := `{"event":"subscription.renewed","data":{"user_id":"` + userID.String() + `","current_period_end":"2030-01-02T03:04:05Z"}}`
+	renewalWithID := `{"id":"evt_1","event":"subscription.renewed","data":{"user_id":"` + userID.String() + `"}}`
+
+	tests := []struct {
+		name     string
+		body     string
+		wantSame bool
+	}{
+		{name: "Same renewal twice without an ID", body: renewal, wantSame: false},
+		{name: "Retried renewal with an ID", body: renewalWithID, wantSame: true},
+	}
+
+	polka := &Polka{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, err := polka.ParseEvent([]byte(tt.body))
+			if err != nil {
+				t.Fatalf("ParseEvent() error = %v", err)
+			}
+			second, err := polka.ParseEvent([]byte(tt.body))
+			if err != nil {
+				t.Fatalf("ParseEvent() error = %v", err)
+			}
+
+			same := first.LedgerID() == second.LedgerID()
+			if same != tt.wantSame {
+				t.Errorf("LedgerID() of both deliveries the same = %v, want %v", same, tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestPolkaVerifyWebhook(t *testing.T) {
+	body := []byte(`{"event":"user.upgraded"}`)
+	timestamp := time.Now().Unix()
+
+	signed := http.Header{}
+	signed.Set(polkaTimestampHeader, strconv.FormatInt(timestamp, 10))
+	signed.Set(polkaSignatureHeader, auth.SignWebhook("secret", timestamp, body))
+
+	apiKey := http.Header{}
+	apiKey.Set("Authorization", "ApiKey legacy-key")
+
+	tests := []struct {
+		name   string
+		polka  *Polka
+		header http.Header
+		hasErr bool
+	}{
+		{name: "Signature", polka: &Polka{Secrets: []string{"secret"}, Tolerance: time.Minute}, header: signed},
+		{name: "Wrong secret", polka: &Polka{Secrets: []string{"other"}, Tolerance: time.Minute}, header: signed, hasErr: true},
+		{name: "Legacy API key", polka: &Polka{LegacyAPIKey: "legacy-key"}, header: apiKey},
+		{name: "Wrong API key", polka: &Polka{LegacyAPIKey: "other-key"}, header: apiKey, hasErr: true},
+		{name: "Signature in legacy mode", polka: &Polka{LegacyAPIKey: "legacy-key"}, header: signed, hasErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.polka.VerifyWebhook(tt.header, body)
+			if (err != nil) != tt.hasErr {
+				t.Errorf("VerifyWebhook() error = %v, wantErr %v", err, tt.hasErr)
+			}
+		})
+	}
+}
+
+func TestFakeRoundTrip(t *testing.T) {
+	fake := &Fake{Secret: "secret"}
+	userID := uuid.New()
+	sent := Event{
+		ID:         "evt_1",
+		Type:       EventPaymentFailed,
+		CustomerID: fake.CustomerID(userID),
+	}
+
+	req, err := fake.NewWebhookRequest("http://localhost/api/webhooks/fake", sent)
+	if err != nil {
+		t.Fatalf("NewWebhookRequest() error = %v", err)
+	}
+	body, _ := io.ReadAll(req.Body)
+
+	if err := fake.VerifyWebhook(req.Header, body); err != nil {
+		t.Fatalf("VerifyWebhook() error = %v", err)
+	}
+	if err := (&Fake{Secret: "other"}).VerifyWebhook(req.Header, body); err == nil {
+		t.Errorf("VerifyWebhook() with the wrong secret succeeded")
+	}
+
+	got, err := fake.ParseEvent(body)
+	if err != nil {
+		t.Fatalf("ParseEvent() error = %v", err)
+	}
+	if got.ID != sent.ID || got.Type != sent.Type {
+		t.Errorf("ParseEvent() = %+v, want %+v", got, sent)
+	}
+	if id, err := fake.UserID(got.CustomerID); err != nil || id != userID {
+		t.Errorf("UserID() = %v, %v, want %v", id, err, userID)
+	}
+}
@@ -0,0 +1,99 @@
+package payments
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/M-Sviridov/chirpy/internal/auth"
+	"github.com/google/uuid"
+)
+
+const (
+	polkaTimestampHeader = "X-Polka-Timestamp"
+	polkaSignatureHeader = "X-Polka-Signature"
+)
+
+// polkaEventTypes maps Polka's event types to ours.
+var polkaEventTypes = map[string]string{
+	"user.upgraded":        EventSubscriptionActivated,
+	"subscription.renewed": EventSubscriptionRenewed,
+	"user.downgraded":      EventSubscriptionCanceled,
+	"payment.failed":       EventPaymentFailed,
+	"payment.refunded":     EventPaymentRefunded,
+}
+
+// Polka is the Polka payment provider. Its webhooks are authenticated by
+// an HMAC signature over the body and a timestamp, or, if LegacyAPIKey is
+// set, by the static API key Polka used to send. Polka refers to customers
+// by their Chirpy user ID.
+type Polka struct {
+	// Secrets are the webhook signing secrets. Every one is accepted, so
+	// a new one can be added before the old one is retired.
+	Secrets []string
+	// Tolerance is how far a delivery's timestamp may be from now.
+	Tolerance time.Duration
+	// LegacyAPIKey, if set, is checked instead of signatures.
+	LegacyAPIKey string
+}
+
+func (p *Polka) Name() string {
+	return "polka"
+}
+
+func (p *Polka) VerifyWebhook(header http.Header, body []byte) error {
+	if p.LegacyAPIKey != "" {
+		apiKey, err := auth.GetAPIKey(header)
+		if err != nil {
+			return err
+		}
+		if subtle.ConstantTimeCompare([]byte(apiKey), []byte(p.LegacyAPIKey)) != 1 {
+			return errors.New("invalid api key")
+		}
+		return nil
+	}
+
+	return auth.VerifyWebhook(
+		p.Secrets,
+		header.Get(polkaTimestampHeader),
+		header.Get(polkaSignatureHeader),
+		body,
+		p.Tolerance,
+		time.Now(),
+	)
+}
+
+func (p *Polka) ParseEvent(body []byte) (Event, error) {
+	var params struct {
+		ID    string `json:"id"`
+		Event string `json:"event"`
+		Data  struct {
+			UserID           string     `json:"user_id"`
+			Plan             string     `json:"plan"`
+			CurrentPeriodEnd *time.Time `json:"current_period_end"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &params); err != nil {
+		return Event{}, fmt.Errorf("couldn't decode Polka event: %w", err)
+	}
+
+	event := Event{
+		ID:           strings.TrimSpace(params.ID),
+		Type:         polkaEventTypes[params.Event],
+		ProviderType: params.Event,
+		CustomerID:   params.Data.UserID,
+		Plan:         params.Data.Plan,
+	}
+	if params.Data.CurrentPeriodEnd != nil {
+		event.CurrentPeriodEnd = *params.Data.CurrentPeriodEnd
+	}
+	return event, nil
+}
+
+func (p *Polka) UserID(customerID string) (uuid.UUID, error) {
+	return uuid.Parse(customerID)
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// runPeriodically runs job every interval until ctx is done. Failures are
+// logged and retried on the next tick.
+func runPeriodically(ctx context.Context, name string, interval time.Duration, job func(context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := job(ctx); err != nil {
+			log.Printf("error running %s: %s", name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
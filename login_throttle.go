@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/M-Sviridov/chirpy/internal/auth"
+	"github.com/M-Sviridov/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// loginFailureWindow is how long failed login attempts are remembered. It
+// must exceed the longest lockout so repeated lockouts keep escalating.
+const loginFailureWindow = time.Hour
+
+type loginThrottleRule struct {
+	name         string
+	freeAttempts int
+	base         time.Duration
+	max          time.Duration
+}
+
+var (
+	accountLoginThrottle = loginThrottleRule{name: "account", freeAttempts: 5, base: 30 * time.Second, max: 15 * time.Minute}
+	ipLoginThrottle      = loginThrottleRule{name: "ip", freeAttempts: 20, base: 30 * time.Second, max: 15 * time.Minute}
+	// verificationMailThrottle limits how often verification mail can be
+	// resent to an address, so the endpoint can't be used to flood it.
+	verificationMailThrottle = loginThrottleRule{name: "verification_mail", freeAttempts: 2, base: 5 * time.Minute, max: time.Hour}
+)
+
+// loginThrottle tracks failed logins for one account or client IP address,
+// or other rate limited attempts, such as mail sent to one address. The
+// state is kept in Postgres so limits hold across instances.
+type loginThrottle struct {
+	key  string
+	rule loginThrottleRule
+}
+
+func loginThrottles(r *http.Request, email string) []loginThrottle {
+	return []loginThrottle{
+		{key: "account:" + strings.ToLower(strings.TrimSpace(email)), rule: accountLoginThrottle},
+		{key: "ip:" + clientIP(r), rule: ipLoginThrottle},
+	}
+}
+
+// reauthThrottles are the throttles for a signed in user confirming their
+// password. The account is keyed by ID, since the email address it's
+// signed in with may be about to change.
+func reauthThrottles(r *http.Request, userID uuid.UUID) []loginThrottle {
+	return []loginThrottle{
+		{key: "user:" + userID.String(), rule: accountLoginThrottle},
+		{key: "ip:" + clientIP(r), rule: ipLoginThrottle},
+	}
+}
+
+// reauthenticate checks the current password a caller gave to confirm a
+// sensitive change to user's account, as auth.Reauthenticate does. Wrong
+// guesses are throttled and recorded like failed logins, so a stolen
+// access token can't be used to guess the password. It writes an error
+// response and returns false if the caller can't go ahead.
+func (cfg *apiConfig) reauthenticate(w http.ResponseWriter, r *http.Request, p principal, user database.User, currentPassword string) bool {
+	throttles := reauthThrottles(r, user.ID)
+	retryAfter, err := cfg.loginRetryAfter(r.Context(), throttles)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't check login attempts")
+		return false
+	}
+	if retryAfter > 0 {
+		respondWithTooManyLoginAttempts(w, retryAfter)
+		return false
+	}
+
+	err = auth.Reauthenticate(p.delegated(), currentPassword, user.HashedPassword)
+	switch {
+	case errors.Is(err, auth.ErrReauthDelegated):
+		respondWithError(w, http.StatusForbidden, err.Error())
+		return false
+	case errors.Is(err, auth.ErrReauthPasswordMissing):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return false
+	case err != nil:
+		userID := uuid.NullUUID{UUID: user.ID, Valid: true}
+		if err := cfg.recordLoginFailure(r, throttles, userID, "reauthentication"); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "couldn't record login attempt")
+			return false
+		}
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return false
+	}
+
+	if err := cfg.clearLoginFailures(r.Context(), throttles); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't reset login attempts")
+		return false
+	}
+	return true
+}
+
+// loginRetryAfter returns how long until any of throttles allows another
+// login attempt, or 0 if none is locked.
+func (cfg *apiConfig) loginRetryAfter(ctx context.Context, throttles []loginThrottle) (time.Duration, error) {
+	now := time.Now().UTC()
+
+	var retryAfter time.Duration
+	for _, t := range throttles {
+		state, err := cfg.db.GetLoginThrottle(ctx, t.key)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		if state.LockedUntil.Valid && state.LockedUntil.Time.After(now) {
+			retryAfter = max(retryAfter, state.LockedUntil.Time.Sub(now))
+		}
+	}
+
+	return retryAfter, nil
+}
+
+// recordLoginFailure records a failed login attempt in the audit log, counts
+// it against each of throttles and locks any that ran out of free attempts.
+// The login step that failed is given by reason.
+func (cfg *apiConfig) recordLoginFailure(r *http.Request, throttles []loginThrottle, userID uuid.NullUUID, reason string) error {
+	now := time.Now().UTC()
+
+	cfg.recordAuditEvent(r, auditEvent{
+		eventType: auditLoginFailed,
+		userID:    userID,
+		actorID:   userID,
+		details:   map[string]any{"reason": reason},
+	})
+
+	for _, t := range throttles {
+		failureParams := database.RecordLoginFailureParams{
+			ThrottleKey: t.key,
+			FailedAt:    now,
+			WindowStart: now.Add(-loginFailureWindow),
+		}
+		state, err := cfg.db.RecordLoginFailure(r.Context(), failureParams)
+		if err != nil {
+			return fmt.Errorf("couldn't record login failure: %w", err)
+		}
+
+		backoff := auth.LoginBackoff(int(state.Failures), t.rule.freeAttempts, t.rule.base, t.rule.max)
+		if backoff == 0 {
+			continue
+		}
+
+		lockParams := database.LockLoginParams{
+			LockedUntil: now.Add(backoff),
+			ThrottleKey: t.key,
+		}
+		if err := cfg.db.LockLogin(r.Context(), lockParams); err != nil {
+			return fmt.Errorf("couldn't lock login: %w", err)
+		}
+
+		eventUserID := uuid.NullUUID{}
+		if t.rule == accountLoginThrottle {
+			eventUserID = userID
+		}
+		cfg.recordAuditEvent(r, auditEvent{
+			eventType: auditLoginLockout,
+			userID:    eventUserID,
+			details: map[string]any{
+				"throttle":     t.rule.name,
+				"failures":     state.Failures,
+				"locked_until": lockParams.LockedUntil,
+			},
+		})
+	}
+
+	return nil
+}
+
+// throttleAttempt counts an attempt at something rate limited, such as
+// sending mail, against t and reports whether it may go ahead. Attempts
+// past the rule's free ones lock t the same way failed logins do.
+func (cfg *apiConfig) throttleAttempt(ctx context.Context, t loginThrottle) (bool, error) {
+	now := time.Now().UTC()
+
+	state, err := cfg.db.GetLoginThrottle(ctx, t.key)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return false, err
+	}
+	if err == nil && state.LockedUntil.Valid && state.LockedUntil.Time.After(now) {
+		return false, nil
+	}
+
+	state, err = cfg.db.RecordLoginFailure(ctx, database.RecordLoginFailureParams{
+		ThrottleKey: t.key,
+		FailedAt:    now,
+		WindowStart: now.Add(-loginFailureWindow),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	backoff := auth.LoginBackoff(int(state.Failures), t.rule.freeAttempts, t.rule.base, t.rule.max)
+	if backoff > 0 {
+		lockParams := database.LockLoginParams{
+			LockedUntil: now.Add(backoff),
+			ThrottleKey: t.key,
+		}
+		if err := cfg.db.LockLogin(ctx, lockParams); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// clearLoginFailures forgets the failed attempts against the account after a
+// successful login. Per-IP failures are kept, otherwise an attacker could
+// reset them by logging into an account of their own.
+func (cfg *apiConfig) clearLoginFailures(ctx context.Context, throttles []loginThrottle) error {
+	for _, t := range throttles {
+		if t.rule != accountLoginThrottle {
+			continue
+		}
+		if err := cfg.db.ClearLoginThrottle(ctx, t.key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// purgeLoginThrottles removes throttles whose failures and lockouts have
+// all lapsed.
+func (cfg *apiConfig) purgeLoginThrottles(ctx context.Context) error {
+	return cfg.db.DeleteStaleLoginThrottles(ctx, time.Now().UTC().Add(-loginFailureWindow))
+}
+
+func respondWithTooManyLoginAttempts(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(retryAfter.Seconds()))))
+	respondWithError(w, http.StatusTooManyRequests, "too many failed login attempts, try again later")
+}
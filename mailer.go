@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/M-Sviridov/chirpy/internal/mail"
+)
+
+// newMailer configures email delivery from the environment. MAIL_DRIVER is
+// one of "smtp", "file" or "memory"; it defaults to "file" on the dev
+// platform.
+func newMailer(platform string) (mail.Mailer, error) {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "Chirpy <no-reply@chirpy.local>"
+	}
+
+	driver := os.Getenv("MAIL_DRIVER")
+	if driver == "" && platform == "dev" {
+		driver = "file"
+	}
+
+	switch driver {
+	case "smtp":
+		addr := os.Getenv("SMTP_ADDR")
+		if addr == "" {
+			return nil, fmt.Errorf("SMTP_ADDR cannot be empty")
+		}
+		return mail.NewSMTPMailer(addr, from, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"))
+	case "file":
+		dir := os.Getenv("MAIL_OUTBOX_DIR")
+		if dir == "" {
+			dir = "outbox"
+		}
+		return mail.NewFileMailer(dir, from)
+	case "memory":
+		return &mail.Outbox{}, nil
+	case "":
+		return nil, fmt.Errorf("MAIL_DRIVER cannot be empty")
+	default:
+		return nil, fmt.Errorf("unknown MAIL_DRIVER %q", driver)
+	}
+}
+
+// sendMail delivers msg in the background, so the response time of the
+// request that triggered it doesn't reveal whether an email was sent.
+func (cfg *apiConfig) sendMail(msg mail.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := cfg.mailer.Send(ctx, msg); err != nil {
+			log.Printf("error sending %q email: %s", msg.Subject, err)
+		}
+	}()
+}
@@ -1,13 +1,20 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"net/http"
 	"os"
+	"strings"
 	"sync/atomic"
+	"time"
 
+	"github.com/M-Sviridov/chirpy/internal/auth"
 	"github.com/M-Sviridov/chirpy/internal/database"
+	"github.com/M-Sviridov/chirpy/internal/mail"
+	"github.com/M-Sviridov/chirpy/internal/payments"
+	"github.com/alexedwards/argon2id"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
@@ -15,9 +22,29 @@ import (
 type apiConfig struct {
 	fileserverHits atomic.Int32
 	db             *database.Queries
+	dbConn         *sql.DB
 	platform       string
-	tokenSecret    string
-	polkaKey       string
+	jwtKeys        *auth.Keyring
+	tokenHashKey   string
+	// totpKey encrypts users' TOTP secrets at rest.
+	totpKey          []byte
+	paymentProviders map[string]payments.Provider
+	mailer           mail.Mailer
+	webhookClient    *http.Client
+	publicURL        string
+	unverifiedAccess string
+	passwordParams   *argon2id.Params
+	passwordPolicy   auth.PasswordPolicy
+	// subscriptionGraceDays is how long a past due subscription keeps its
+	// benefits while the payment is retried.
+	subscriptionGraceDays int32
+	// accountDeletionGraceDays is how long a deleted account can still be
+	// recovered by logging back in.
+	accountDeletionGraceDays int32
+	secureCookies            bool
+	// dummyPasswordHash is compared against when a login names an unknown
+	// account, so response times don't reveal which accounts exist.
+	dummyPasswordHash string
 }
 
 func main() {
@@ -25,6 +52,13 @@ func main() {
 	const filePathRoot = "."
 
 	godotenv.Load()
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1], os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	dbUrl := os.Getenv("DB_URL")
 	if dbUrl == "" {
 		log.Fatal("DB_URL cannot be empty")
@@ -34,12 +68,66 @@ func main() {
 		log.Fatal("PLATFORM cannot be empty")
 	}
 	tokenSecret := os.Getenv("TOKEN_SECRET")
-	if tokenSecret == "" {
-		log.Fatal("TOKEN_SECRET cannot be empty")
+	jwtKeySpec := os.Getenv("JWT_KEYS")
+	if tokenSecret == "" && jwtKeySpec == "" {
+		log.Fatal("TOKEN_SECRET and JWT_KEYS cannot both be empty")
+	}
+	jwtKeys, err := auth.LoadKeyring(jwtKeySpec, os.Getenv("JWT_ACTIVE_KEY"), tokenSecret)
+	if err != nil {
+		log.Fatalf("error loading JWT keys: %s", err)
+	}
+	tokenHashKey := os.Getenv("TOKEN_HASH_KEY")
+	if tokenHashKey == "" {
+		log.Fatal("TOKEN_HASH_KEY cannot be empty")
+	}
+	// TOTP secrets have to be recovered to check codes, so unlike other
+	// credentials they can't be hashed; they're encrypted with this key.
+	totpKey, err := auth.ParseSecretKey(os.Getenv("TOTP_ENCRYPTION_KEY"))
+	if err != nil {
+		log.Fatalf("error loading TOTP_ENCRYPTION_KEY: %s", err)
+	}
+	paymentProviders, err := paymentProvidersFromEnv(platform)
+	if err != nil {
+		log.Fatalf("error configuring payment providers: %s", err)
+	}
+
+	publicURL := os.Getenv("PUBLIC_URL")
+	if publicURL == "" {
+		publicURL = "http://localhost" + listenAddr
+	}
+	// Cookies can only be marked Secure when the app is served over https,
+	// which a plain http PUBLIC_URL rules out.
+	secureCookies := !strings.HasPrefix(publicURL, "http://")
+	unverifiedAccess := os.Getenv("UNVERIFIED_ACCOUNT_ACCESS")
+	if unverifiedAccess == "" {
+		unverifiedAccess = unverifiedAccessReadOnly
+	}
+	if err := validateUnverifiedAccess(unverifiedAccess); err != nil {
+		log.Fatal(err)
+	}
+	passwordParams, err := passwordParamsFromEnv()
+	if err != nil {
+		log.Fatalf("error configuring password hashing: %s", err)
+	}
+	passwordPolicy, err := passwordPolicyFromEnv()
+	if err != nil {
+		log.Fatalf("error configuring password policy: %s", err)
+	}
+	dummyPasswordHash, err := auth.HashPassword("chirpy-dummy-password", passwordParams)
+	if err != nil {
+		log.Fatalf("error hashing dummy password: %s", err)
+	}
+	subscriptionGraceDays, err := subscriptionGraceDaysFromEnv()
+	if err != nil {
+		log.Fatal(err)
 	}
-	polkaKey := os.Getenv("POLKA_KEY")
-	if tokenSecret == "" {
-		log.Fatal("POLKA_KEY cannot be empty")
+	accountDeletionGraceDays, err := accountDeletionGraceDaysFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	mailer, err := newMailer(platform)
+	if err != nil {
+		log.Fatalf("error configuring mailer: %s", err)
 	}
 
 	db, err := sql.Open("postgres", dbUrl)
@@ -49,31 +137,106 @@ func main() {
 	dbQueries := database.New(db)
 
 	apiCfg := apiConfig{
-		fileserverHits: atomic.Int32{},
-		db:             dbQueries,
-		platform:       platform,
-		tokenSecret:    tokenSecret,
-		polkaKey:       polkaKey,
+		fileserverHits:           atomic.Int32{},
+		db:                       dbQueries,
+		dbConn:                   db,
+		platform:                 platform,
+		jwtKeys:                  jwtKeys,
+		tokenHashKey:             tokenHashKey,
+		totpKey:                  totpKey,
+		paymentProviders:         paymentProviders,
+		mailer:                   mailer,
+		webhookClient:            newWebhookClient(platform == "dev"),
+		publicURL:                strings.TrimSuffix(publicURL, "/"),
+		unverifiedAccess:         unverifiedAccess,
+		passwordParams:           passwordParams,
+		passwordPolicy:           passwordPolicy,
+		subscriptionGraceDays:    subscriptionGraceDays,
+		accountDeletionGraceDays: accountDeletionGraceDays,
+		secureCookies:            secureCookies,
+		dummyPasswordHash:        dummyPasswordHash,
 	}
 
+	if err := apiCfg.hashLegacyRefreshTokens(context.Background()); err != nil {
+		log.Fatalf("error migrating refresh tokens: %s", err)
+	}
+	if err := apiCfg.encryptLegacyTOTPSecrets(context.Background()); err != nil {
+		log.Fatalf("error migrating TOTP secrets: %s", err)
+	}
+
+	go runPeriodically(context.Background(), "revoked access token purge", time.Hour, apiCfg.purgeRevokedAccessTokens)
+	go runPeriodically(context.Background(), "login throttle purge", time.Hour, apiCfg.purgeLoginThrottles)
+	go runPeriodically(context.Background(), "OAuth authorization code purge", time.Hour, apiCfg.purgeOAuthAuthorizationCodes)
+	go runPeriodically(context.Background(), "lapsed subscription expiry", time.Hour, apiCfg.expireLapsedSubscriptions)
+	go runPeriodically(context.Background(), "webhook delivery", 10*time.Second, apiCfg.deliverWebhooks)
+	go runPeriodically(context.Background(), "scheduled account deletion", time.Hour, apiCfg.deleteScheduledAccounts)
+	go runPeriodically(context.Background(), "data export build", 10*time.Second, apiCfg.buildDataExports)
+	go runPeriodically(context.Background(), "expired data export purge", time.Hour, apiCfg.purgeExpiredDataExports)
+
 	mux := http.NewServeMux()
 	mux.Handle("/app/", apiCfg.middlewareMetricsInc(http.StripPrefix("/app/", http.FileServer(http.Dir(filePathRoot)))))
-	mux.HandleFunc("GET /admin/metrics", apiCfg.handleMetrics)
-	mux.HandleFunc("POST /admin/reset", apiCfg.handleReset)
+	mux.Handle("GET /admin/metrics", apiCfg.middlewareRequireRole(roleAdmin, http.HandlerFunc(apiCfg.handleMetrics)))
+	mux.Handle("POST /admin/reset", apiCfg.middlewareRequireRole(roleAdmin, http.HandlerFunc(apiCfg.handleReset)))
+	mux.Handle("GET /admin/audit-events", apiCfg.middlewareRequireRole(roleAdmin, http.HandlerFunc(apiCfg.handleGetAuditEvents)))
+	mux.Handle("GET /admin/webhook-events", apiCfg.middlewareRequireRole(roleAdmin, http.HandlerFunc(apiCfg.handleGetWebhookEvents)))
+	mux.Handle("POST /admin/webhook-events/{eventID}/replay", apiCfg.middlewareRequireRole(roleAdmin, http.HandlerFunc(apiCfg.handleReplayWebhookEvent)))
+	mux.Handle("GET /admin/users", apiCfg.middlewareRequireRole(roleAdmin, http.HandlerFunc(apiCfg.handleAdminGetUsers)))
+	mux.Handle("GET /admin/users/{userID}", apiCfg.middlewareRequireRole(roleAdmin, http.HandlerFunc(apiCfg.handleAdminGetUser)))
+	mux.Handle("POST /admin/users/{userID}/suspend", apiCfg.middlewareRequireRole(roleAdmin, http.HandlerFunc(apiCfg.handleAdminSuspendUser)))
+	mux.Handle("POST /admin/users/{userID}/ban", apiCfg.middlewareRequireRole(roleAdmin, http.HandlerFunc(apiCfg.handleAdminBanUser)))
+	mux.Handle("POST /admin/users/{userID}/unsuspend", apiCfg.middlewareRequireRole(roleAdmin, http.HandlerFunc(apiCfg.handleAdminUnsuspendUser)))
+	mux.Handle("POST /admin/users/{userID}/password-reset", apiCfg.middlewareRequireRole(roleAdmin, http.HandlerFunc(apiCfg.handleAdminResetUserPassword)))
+	mux.Handle("POST /admin/users/{userID}/revoke-sessions", apiCfg.middlewareRequireRole(roleAdmin, http.HandlerFunc(apiCfg.handleAdminRevokeUserSessions)))
+	mux.Handle("PUT /admin/users/{userID}/chirpy-red", apiCfg.middlewareRequireRole(roleAdmin, http.HandlerFunc(apiCfg.handleAdminSetChirpyRed)))
+	mux.Handle("PUT /admin/users/{userID}/role", apiCfg.middlewareRequireRole(roleAdmin, http.HandlerFunc(apiCfg.handleAdminSetUserRole)))
 	mux.HandleFunc("GET /api/healthz", handleReadiness)
+	mux.HandleFunc("GET /.well-known/jwks.json", apiCfg.handleJWKS)
 	mux.HandleFunc("POST /api/users", apiCfg.handleCreateUser)
 	mux.HandleFunc("POST /api/chirps", apiCfg.handleCreateChirp)
 	mux.HandleFunc("GET /api/chirps", apiCfg.handleGetChirps)
 	mux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.handleGetChirp)
 	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.handleDeleteChirp)
 	mux.HandleFunc("POST /api/login", apiCfg.handleLogin)
+	mux.HandleFunc("POST /api/login/2fa", apiCfg.handleLoginTwoFactor)
 	mux.HandleFunc("POST /api/refresh", apiCfg.handleRefresh)
 	mux.HandleFunc("POST /api/revoke", apiCfg.handleRevoke)
+	mux.HandleFunc("POST /api/logout", apiCfg.handleLogout)
 	mux.HandleFunc("PUT /api/users", apiCfg.handleUpdateUser)
-	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.handleWebhooks)
+	mux.HandleFunc("PATCH /api/users", apiCfg.handlePatchUser)
+	mux.HandleFunc("DELETE /api/users/me", apiCfg.handleDeleteUser)
+	mux.HandleFunc("POST /api/users/verify", apiCfg.handleVerifyEmail)
+	mux.HandleFunc("POST /api/users/verify/resend", apiCfg.handleResendEmailVerification)
+	mux.HandleFunc("POST /api/password/forgot", apiCfg.handleForgotPassword)
+	mux.HandleFunc("POST /api/password/reset", apiCfg.handleResetPassword)
+	mux.HandleFunc("POST /api/webhooks/{provider}", apiCfg.handleWebhooks)
+	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.handlePolkaWebhooks)
+	mux.HandleFunc("POST /api/2fa/enroll", apiCfg.handleEnrollTwoFactor)
+	mux.HandleFunc("POST /api/2fa/confirm", apiCfg.handleConfirmTwoFactor)
+	mux.HandleFunc("POST /api/2fa/disable", apiCfg.handleDisableTwoFactor)
+	mux.HandleFunc("GET /api/sessions", apiCfg.handleGetSessions)
+	mux.HandleFunc("DELETE /api/sessions/{sessionID}", apiCfg.handleRevokeSession)
+	mux.HandleFunc("POST /api/sessions/revoke-all", apiCfg.handleRevokeAllSessions)
+	mux.HandleFunc("GET /api/me/chirps", apiCfg.handleGetMyChirps)
+	mux.HandleFunc("GET /api/me/security-events", apiCfg.handleGetSecurityEvents)
+	mux.HandleFunc("POST /api/me/export", apiCfg.handleCreateDataExport)
+	mux.HandleFunc("GET /api/me/export/{exportID}", apiCfg.handleGetDataExport)
+	mux.HandleFunc("POST /api/tokens", apiCfg.handleCreateToken)
+	mux.HandleFunc("GET /api/tokens", apiCfg.handleGetTokens)
+	mux.HandleFunc("DELETE /api/tokens/{tokenID}", apiCfg.handleRevokeToken)
+	mux.HandleFunc("POST /api/oauth/clients", apiCfg.handleCreateOAuthClient)
+	mux.HandleFunc("GET /api/oauth/clients", apiCfg.handleGetOAuthClients)
+	mux.HandleFunc("DELETE /api/oauth/clients/{clientID}", apiCfg.handleDeleteOAuthClient)
+	mux.HandleFunc("GET /api/oauth/authorize", apiCfg.handleGetAuthorization)
+	mux.HandleFunc("POST /api/oauth/authorize", apiCfg.handleAuthorize)
+	mux.HandleFunc("POST /api/oauth/token", apiCfg.handleOAuthToken)
+	mux.HandleFunc("POST /api/webhook-endpoints", apiCfg.handleCreateWebhookEndpoint)
+	mux.HandleFunc("GET /api/webhook-endpoints", apiCfg.handleGetWebhookEndpoints)
+	mux.HandleFunc("DELETE /api/webhook-endpoints/{endpointID}", apiCfg.handleDeleteWebhookEndpoint)
+	mux.HandleFunc("POST /api/webhook-endpoints/{endpointID}/test", apiCfg.handleTestWebhookEndpoint)
+	mux.HandleFunc("GET /api/webhook-endpoints/{endpointID}/deliveries", apiCfg.handleGetWebhookDeliveries)
 
 	srv := http.Server{
-		Handler: mux,
+		Handler: middlewareRequestID(mux),
 		Addr:    listenAddr,
 	}
 
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/M-Sviridov/chirpy/internal/auth"
+	"github.com/M-Sviridov/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// OAuth error codes from RFC 6749.
+const (
+	oauthErrorInvalidRequest          = "invalid_request"
+	oauthErrorInvalidClient           = "invalid_client"
+	oauthErrorInvalidGrant            = "invalid_grant"
+	oauthErrorInvalidScope            = "invalid_scope"
+	oauthErrorAccessDenied            = "access_denied"
+	oauthErrorUnsupportedGrantType    = "unsupported_grant_type"
+	oauthErrorUnsupportedResponseType = "unsupported_response_type"
+)
+
+func respondWithOAuthError(w http.ResponseWriter, code int, oauthError, description string) {
+	type errorResponse struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description,omitempty"`
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, code, errorResponse{
+		Error:            oauthError,
+		ErrorDescription: description,
+	})
+}
+
+// validateRedirectURI checks that a client's redirect URI is an absolute
+// https URL, or an http URL on the loopback interface for native apps.
+func validateRedirectURI(rawURI string) error {
+	u, err := url.Parse(rawURI)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("redirect URI %q is not an absolute URL", rawURI)
+	}
+	if u.Fragment != "" {
+		return fmt.Errorf("redirect URI %q must not have a fragment", rawURI)
+	}
+
+	switch u.Scheme {
+	case "https":
+		return nil
+	case "http":
+		host := u.Hostname()
+		if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("redirect URI %q must use https", rawURI)
+}
+
+// parseScope parses a space separated OAuth scope parameter.
+func parseScope(scope string) ([]string, error) {
+	return validateScopes(strings.Fields(scope))
+}
+
+// oauthRedirect adds params to the query of a client's redirect URI.
+func oauthRedirect(redirectURI string, params url.Values) string {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+
+	query := u.Query()
+	for key, values := range params {
+		for _, v := range values {
+			if v != "" {
+				query.Add(key, v)
+			}
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+// authorizationRequest is a validated OAuth authorization request.
+type authorizationRequest struct {
+	client        database.OauthClient
+	redirectURI   string
+	scopes        []string
+	state         string
+	codeChallenge string
+}
+
+// authorizationParams are the parameters of an authorization request, as
+// sent in the query of the consent screen and the body of the decision.
+type authorizationParams struct {
+	ResponseType        string `json:"response_type"`
+	ClientID            string `json:"client_id"`
+	RedirectURI         string `json:"redirect_uri"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+}
+
+// authorizationError is a problem with an authorization request. If
+// redirect is set, the client and redirect URI checked out and the user
+// should be sent back to the client with the error.
+type authorizationError struct {
+	oauthError  string
+	description string
+	redirect    string
+}
+
+func (e *authorizationError) Error() string {
+	return e.description
+}
+
+// validateAuthorization checks an authorization request against the
+// client it names.
+func (cfg *apiConfig) validateAuthorization(ctx context.Context, params authorizationParams) (authorizationRequest, error) {
+	clientID, err := uuid.Parse(params.ClientID)
+	if err != nil {
+		return authorizationRequest{}, &authorizationError{oauthError: oauthErrorInvalidClient, description: "unknown client"}
+	}
+	client, err := cfg.db.GetOAuthClient(ctx, clientID)
+	if err != nil {
+		return authorizationRequest{}, &authorizationError{oauthError: oauthErrorInvalidClient, description: "unknown client"}
+	}
+	// Nothing is ever redirected to a URI the client didn't register.
+	if !slices.Contains(client.RedirectUris, params.RedirectURI) {
+		return authorizationRequest{}, &authorizationError{oauthError: oauthErrorInvalidRequest, description: "redirect_uri is not registered for this client"}
+	}
+
+	redirectWithError := func(oauthError, description string) error {
+		return &authorizationError{
+			oauthError:  oauthError,
+			description: description,
+			redirect: oauthRedirect(params.RedirectURI, url.Values{
+				"error":             {oauthError},
+				"error_description": {description},
+				"state":             {params.State},
+			}),
+		}
+	}
+
+	if params.ResponseType != "code" {
+		return authorizationRequest{}, redirectWithError(oauthErrorUnsupportedResponseType, "response_type must be code")
+	}
+	scopes, err := parseScope(params.Scope)
+	if err != nil {
+		return authorizationRequest{}, redirectWithError(oauthErrorInvalidScope, err.Error())
+	}
+	if params.CodeChallengeMethod != "S256" || !auth.ValidPKCEChallenge(params.CodeChallenge) {
+		return authorizationRequest{}, redirectWithError(oauthErrorInvalidRequest, "an S256 PKCE code_challenge is required")
+	}
+
+	return authorizationRequest{
+		client:        client,
+		redirectURI:   params.RedirectURI,
+		scopes:        scopes,
+		state:         params.State,
+		codeChallenge: params.CodeChallenge,
+	}, nil
+}
+
+// respondWithAuthorizationError reports a rejected authorization request,
+// including where to send the user back to if the client can be told.
+func respondWithAuthorizationError(w http.ResponseWriter, err error) {
+	type errorResponse struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+		RedirectTo       string `json:"redirect_to,omitempty"`
+	}
+
+	var authErr *authorizationError
+	if !errors.As(err, &authErr) {
+		respondWithError(w, http.StatusInternalServerError, "couldn't validate authorization request")
+		return
+	}
+
+	respondWithJSON(w, http.StatusBadRequest, errorResponse{
+		Error:            authErr.oauthError,
+		ErrorDescription: authErr.description,
+		RedirectTo:       authErr.redirect,
+	})
+}
+
+// authenticateClient checks the credentials a client presents at the token
+// endpoint. Confidential clients must send their secret, either with HTTP
+// Basic authentication or in the form; public clients rely on PKCE alone.
+func (cfg *apiConfig) authenticateClient(r *http.Request) (database.OauthClient, error) {
+	clientID, secret, basic := r.BasicAuth()
+	if !basic {
+		clientID = r.PostFormValue("client_id")
+		secret = r.PostFormValue("client_secret")
+	}
+
+	id, err := uuid.Parse(clientID)
+	if err != nil {
+		return database.OauthClient{}, errors.New("unknown client")
+	}
+	client, err := cfg.db.GetOAuthClient(r.Context(), id)
+	if err != nil {
+		return database.OauthClient{}, errors.New("unknown client")
+	}
+
+	if client.SecretHash.Valid {
+		hash := auth.HashToken(secret, cfg.tokenHashKey)
+		if subtle.ConstantTimeCompare([]byte(hash), []byte(client.SecretHash.String)) != 1 {
+			return database.OauthClient{}, errors.New("invalid client secret")
+		}
+	}
+
+	return client, nil
+}
+
+func (cfg *apiConfig) purgeOAuthAuthorizationCodes(ctx context.Context) error {
+	return cfg.db.DeleteExpiredOAuthAuthorizationCodes(ctx)
+}
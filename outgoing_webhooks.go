@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/M-Sviridov/chirpy/internal/auth"
+	"github.com/M-Sviridov/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Events users can have delivered to their webhook endpoints. Ping is only
+// ever sent on request, to test an endpoint.
+const (
+	webhookEventChirpCreated = "chirp.created"
+	webhookEventChirpDeleted = "chirp.deleted"
+	webhookEventPing         = "ping"
+)
+
+var webhookEventTypes = []string{webhookEventChirpCreated, webhookEventChirpDeleted}
+
+// Deliveries are signed the same way as the webhooks Chirpy receives: an
+// HMAC-SHA256 over "<timestamp>.<body>" with the endpoint's secret.
+const (
+	webhookEventHeader     = "X-Chirpy-Event"
+	webhookDeliveryHeader  = "X-Chirpy-Delivery"
+	webhookTimestampHeader = "X-Chirpy-Timestamp"
+	webhookSignatureHeader = "X-Chirpy-Signature"
+
+	webhookSecretPrefix = "whsec_"
+)
+
+// A failed delivery is retried with exponential backoff until it has been
+// attempted maxWebhookDeliveryAttempts times, after which it's dead and
+// left for the endpoint's owner to look into.
+const (
+	maxWebhookDeliveryAttempts = 8
+	webhookRetryBase           = 30 * time.Second
+	webhookRetryMax            = 6 * time.Hour
+
+	webhookDeliveryBatch   = 20
+	webhookDeliveryTimeout = 10 * time.Second
+	// webhookDeliveryLease is how long a claimed delivery is hidden from
+	// other workers. It must outlast attempting a whole batch.
+	webhookDeliveryLease = 5 * time.Minute
+)
+
+type webhookPayload struct {
+	ID        uuid.UUID `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+func newWebhookPayload(eventID uuid.UUID, eventType string, data any) (json.RawMessage, error) {
+	return json.Marshal(webhookPayload{
+		ID:        eventID,
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+}
+
+// publishWebhookEvent queues an event about a user for delivery to each of
+// their endpoints subscribed to it. Failing to queue it is logged rather
+// than failing the request.
+func (cfg *apiConfig) publishWebhookEvent(ctx context.Context, userID uuid.UUID, eventType string, data any) {
+	eventID := uuid.New()
+	payload, err := newWebhookPayload(eventID, eventType, data)
+	if err != nil {
+		log.Printf("error marshalling %s webhook event: %s", eventType, err)
+		return
+	}
+
+	deliveryParams := database.EnqueueWebhookDeliveriesParams{
+		EventID:   eventID,
+		EventType: eventType,
+		Payload:   payload,
+		UserID:    userID,
+	}
+	if err := cfg.db.EnqueueWebhookDeliveries(ctx, deliveryParams); err != nil {
+		log.Printf("error queueing %s webhook event: %s", eventType, err)
+	}
+}
+
+// webhookRetryDelay returns how long to wait before the next attempt at a
+// delivery that has failed attempts times.
+func webhookRetryDelay(attempts int32) time.Duration {
+	delay := webhookRetryBase
+	for i := int32(1); i < attempts && delay < webhookRetryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, webhookRetryMax)
+}
+
+// deliverWebhooks attempts the deliveries that are due.
+func (cfg *apiConfig) deliverWebhooks(ctx context.Context) error {
+	claimParams := database.ClaimDueWebhookDeliveriesParams{
+		LeaseSeconds:  int32(webhookDeliveryLease.Seconds()),
+		MaxDeliveries: webhookDeliveryBatch,
+	}
+	deliveries, err := cfg.db.ClaimDueWebhookDeliveries(ctx, claimParams)
+	if err != nil {
+		return err
+	}
+
+	for _, delivery := range deliveries {
+		statusCode, err := cfg.attemptWebhookDelivery(ctx, delivery)
+		lastStatusCode := sql.NullInt32{Int32: int32(statusCode), Valid: statusCode != 0}
+
+		if err == nil {
+			deliveredParams := database.MarkWebhookDeliveryDeliveredParams{
+				ID:             delivery.ID,
+				LastStatusCode: lastStatusCode,
+			}
+			if err := cfg.db.MarkWebhookDeliveryDelivered(ctx, deliveredParams); err != nil {
+				return err
+			}
+			continue
+		}
+
+		failedParams := database.MarkWebhookDeliveryFailedParams{
+			Dead:           delivery.Attempts >= maxWebhookDeliveryAttempts,
+			LastStatusCode: lastStatusCode,
+			LastError:      err.Error(),
+			RetryInSeconds: int32(webhookRetryDelay(delivery.Attempts).Seconds()),
+			ID:             delivery.ID,
+		}
+		if err := cfg.db.MarkWebhookDeliveryFailed(ctx, failedParams); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// attemptWebhookDelivery sends a delivery to its endpoint. Anything but a
+// 2xx response is a failure.
+func (cfg *apiConfig) attemptWebhookDelivery(ctx context.Context, delivery database.ClaimDueWebhookDeliveriesRow) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, webhookDeliveryTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Chirpy-Webhooks/1.0")
+	req.Header.Set(webhookEventHeader, delivery.EventType)
+	req.Header.Set(webhookDeliveryHeader, delivery.ID.String())
+	req.Header.Set(webhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(webhookSignatureHeader, auth.SignWebhook(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := cfg.webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// validateWebhookURL checks that a webhook endpoint URL is an absolute
+// https URL. Plain http is allowed on the dev platform.
+func (cfg *apiConfig) validateWebhookURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("webhook URL %q is not an absolute URL", rawURL)
+	}
+	if u.User != nil || u.Fragment != "" {
+		return fmt.Errorf("webhook URL %q must not have credentials or a fragment", rawURL)
+	}
+	if u.Scheme != "https" && !(u.Scheme == "http" && cfg.platform == "dev") {
+		return fmt.Errorf("webhook URL %q must use https", rawURL)
+	}
+	return nil
+}
+
+var errWebhookAddressNotAllowed = errors.New("webhook endpoints must not resolve to private addresses")
+
+// newWebhookClient returns the client webhooks are delivered with. Unless
+// allowPrivate is set, it refuses to connect to loopback, private and
+// link-local addresses, so endpoints can't be pointed at internal services.
+// The check is made on the address actually dialled, so it also covers
+// names that resolve differently later. Redirects aren't followed.
+func newWebhookClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: webhookDeliveryTimeout,
+		Control: func(network, address string, c syscall.RawConn) error {
+			if allowPrivate {
+				return nil
+			}
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+				ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+				return errWebhookAddressNotAllowed
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   webhookDeliveryTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
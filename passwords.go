@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/M-Sviridov/chirpy/internal/auth"
+	"github.com/M-Sviridov/chirpy/internal/database"
+	"github.com/alexedwards/argon2id"
+)
+
+// passwordParamsFromEnv reads the argon2id parameters new password hashes
+// use. ARGON2_MEMORY is in KiB; unset variables keep the library defaults.
+func passwordParamsFromEnv() (*argon2id.Params, error) {
+	params := *argon2id.DefaultParams
+
+	if v := os.Getenv("ARGON2_MEMORY"); v != "" {
+		memory, err := strconv.ParseUint(v, 10, 32)
+		if err != nil || memory == 0 {
+			return nil, fmt.Errorf("invalid ARGON2_MEMORY %q", v)
+		}
+		params.Memory = uint32(memory)
+	}
+	if v := os.Getenv("ARGON2_ITERATIONS"); v != "" {
+		iterations, err := strconv.ParseUint(v, 10, 32)
+		if err != nil || iterations == 0 {
+			return nil, fmt.Errorf("invalid ARGON2_ITERATIONS %q", v)
+		}
+		params.Iterations = uint32(iterations)
+	}
+	if v := os.Getenv("ARGON2_PARALLELISM"); v != "" {
+		parallelism, err := strconv.ParseUint(v, 10, 8)
+		if err != nil || parallelism == 0 {
+			return nil, fmt.Errorf("invalid ARGON2_PARALLELISM %q", v)
+		}
+		params.Parallelism = uint8(parallelism)
+	}
+
+	// Argon2 needs at least 8 KiB per lane; the library would quietly use
+	// more than configured otherwise.
+	if params.Memory < 8*uint32(params.Parallelism) {
+		return nil, fmt.Errorf("ARGON2_MEMORY must be at least %d KiB for a parallelism of %d", 8*uint32(params.Parallelism), params.Parallelism)
+	}
+
+	return &params, nil
+}
+
+// passwordPolicyFromEnv reads the rules new passwords must follow.
+// PASSWORD_BANNED_WORDS is comma-separated, and BREACHED_PASSWORDS_FILE
+// names an optional corpus of SHA-1 hashes of breached passwords.
+func passwordPolicyFromEnv() (auth.PasswordPolicy, error) {
+	policy := auth.PasswordPolicy{
+		MinLength:   8,
+		MaxLength:   64,
+		BannedWords: []string{"chirpy"},
+	}
+
+	if v := os.Getenv("PASSWORD_MIN_LENGTH"); v != "" {
+		minLength, err := strconv.Atoi(v)
+		if err != nil || minLength < 1 {
+			return policy, fmt.Errorf("invalid PASSWORD_MIN_LENGTH %q", v)
+		}
+		policy.MinLength = minLength
+	}
+	if v := os.Getenv("PASSWORD_MAX_LENGTH"); v != "" {
+		maxLength, err := strconv.Atoi(v)
+		if err != nil || maxLength < policy.MinLength {
+			return policy, fmt.Errorf("invalid PASSWORD_MAX_LENGTH %q", v)
+		}
+		policy.MaxLength = maxLength
+	}
+	if v, ok := os.LookupEnv("PASSWORD_BANNED_WORDS"); ok {
+		policy.BannedWords = nil
+		for _, word := range strings.Split(v, ",") {
+			if word = strings.TrimSpace(word); word != "" {
+				policy.BannedWords = append(policy.BannedWords, word)
+			}
+		}
+	}
+
+	if path := os.Getenv("BREACHED_PASSWORDS_FILE"); path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return policy, err
+		}
+		defer f.Close()
+
+		policy.Breached, err = auth.LoadBreachedPasswords(f)
+		if err != nil {
+			return policy, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	return policy, nil
+}
+
+// checkPassword responds with the rules password breaks and returns false
+// if it doesn't meet the password policy for the account with email.
+func (cfg *apiConfig) checkPassword(w http.ResponseWriter, password, email string) bool {
+	violations := cfg.passwordPolicy.CheckPassword(password, auth.EmailContextWords(email)...)
+	if len(violations) == 0 {
+		return true
+	}
+
+	respondWithPasswordViolations(w, violations)
+	return false
+}
+
+func respondWithPasswordViolations(w http.ResponseWriter, violations []auth.PolicyViolation) {
+	type errorResponse struct {
+		Error      string                 `json:"error"`
+		Violations []auth.PolicyViolation `json:"violations"`
+	}
+
+	respondWithJSON(w, http.StatusBadRequest, errorResponse{
+		Error:      "password does not meet the password policy",
+		Violations: violations,
+	})
+}
+
+// rehashPasswordIfNeeded upgrades the stored hash of a password that was
+// just verified if it was created with weaker parameters than the current
+// ones. Failures are logged; the old hash keeps working.
+func (cfg *apiConfig) rehashPasswordIfNeeded(ctx context.Context, user database.User, password string) {
+	needsRehash, err := auth.NeedsRehash(user.HashedPassword, cfg.passwordParams)
+	if err != nil || !needsRehash {
+		return
+	}
+
+	hash, err := auth.HashPassword(password, cfg.passwordParams)
+	if err != nil {
+		log.Printf("error rehashing password: %s", err)
+		return
+	}
+
+	passwordParams := database.UpdateUserPasswordParams{
+		ID:             user.ID,
+		HashedPassword: hash,
+	}
+	if err := cfg.db.UpdateUserPassword(ctx, passwordParams); err != nil {
+		log.Printf("error saving rehashed password: %s", err)
+	}
+}
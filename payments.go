@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/M-Sviridov/chirpy/internal/payments"
+)
+
+const (
+	defaultPolkaSignatureTolerance = 5 * time.Minute
+	maxWebhookBodyBytes            = 1 << 20
+)
+
+// paymentProvidersFromEnv configures the payment providers webhooks are
+// accepted from, keyed by name.
+func paymentProvidersFromEnv(platform string) (map[string]payments.Provider, error) {
+	polka, err := polkaFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("error configuring Polka: %w", err)
+	}
+	providers := map[string]payments.Provider{
+		polka.Name(): polka,
+	}
+
+	// The fake provider lets anyone who knows its secret grant Chirpy
+	// Red, so it's for local end-to-end tests only.
+	if secret := os.Getenv("FAKE_PAYMENTS_SECRET"); secret != "" {
+		if platform != "dev" {
+			return nil, errors.New("FAKE_PAYMENTS_SECRET can only be set on the dev platform")
+		}
+		fake := &payments.Fake{Secret: secret}
+		providers[fake.Name()] = fake
+	}
+
+	return providers, nil
+}
+
+// polkaFromEnv reads how Polka webhooks are authenticated.
+// POLKA_WEBHOOK_SECRETS is comma-separated; every secret listed is
+// accepted, so a new one can be added before the old one is retired.
+// POLKA_AUTH_MODE=api_key switches to the legacy POLKA_KEY check.
+func polkaFromEnv() (*payments.Polka, error) {
+	polka := &payments.Polka{
+		Tolerance: defaultPolkaSignatureTolerance,
+	}
+
+	switch mode := os.Getenv("POLKA_AUTH_MODE"); mode {
+	case "", "signature":
+		for _, secret := range strings.Split(os.Getenv("POLKA_WEBHOOK_SECRETS"), ",") {
+			if secret = strings.TrimSpace(secret); secret != "" {
+				polka.Secrets = append(polka.Secrets, secret)
+			}
+		}
+		if len(polka.Secrets) == 0 {
+			return nil, errors.New("POLKA_WEBHOOK_SECRETS cannot be empty")
+		}
+	case "api_key":
+		polka.LegacyAPIKey = os.Getenv("POLKA_KEY")
+		if polka.LegacyAPIKey == "" {
+			return nil, errors.New("POLKA_KEY cannot be empty")
+		}
+	default:
+		return nil, fmt.Errorf("invalid POLKA_AUTH_MODE %q", mode)
+	}
+
+	if v := os.Getenv("POLKA_SIGNATURE_TOLERANCE"); v != "" {
+		tolerance, err := time.ParseDuration(v)
+		if err != nil || tolerance <= 0 {
+			return nil, fmt.Errorf("invalid POLKA_SIGNATURE_TOLERANCE %q", v)
+		}
+		polka.Tolerance = tolerance
+	}
+
+	return polka, nil
+}
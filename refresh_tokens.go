@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/M-Sviridov/chirpy/internal/auth"
+	"github.com/M-Sviridov/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	accessTokenExpiry = time.Hour
+	// refreshTokenExpiry matches the expiry CreateRefreshToken sets.
+	refreshTokenExpiry = 60 * 24 * time.Hour
+)
+
+// session is a newly created session and its tokens.
+type session struct {
+	id           uuid.UUID
+	accessToken  string
+	refreshToken string
+}
+
+// createSession stores a new refresh token for the user, along with the client
+// it was issued to, and returns it with an access token bound to it.
+func (cfg *apiConfig) createSession(r *http.Request, userID uuid.UUID) (session, error) {
+	return cfg.createClientSession(r, userID, uuid.Nil, nil)
+}
+
+// createClientSession is createSession for a session of an OAuth client,
+// whose tokens are bound to the client and limited to scopes. A nil
+// clientID creates a first-party session.
+func (cfg *apiConfig) createClientSession(r *http.Request, userID, clientID uuid.UUID, scopes []string) (session, error) {
+	refreshToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		return session{}, fmt.Errorf("couldn't create refresh token: %w", err)
+	}
+
+	refreshTokenParams := database.CreateRefreshTokenParams{
+		TokenHash: auth.HashToken(refreshToken, cfg.tokenHashKey),
+		UserID:    userID,
+		UserAgent: r.UserAgent(),
+		IpAddress: clientIP(r),
+		ClientID:  uuid.NullUUID{UUID: clientID, Valid: clientID != uuid.Nil},
+		Scopes:    append([]string{}, scopes...),
+	}
+
+	rt, err := cfg.db.CreateRefreshToken(r.Context(), refreshTokenParams)
+	if err != nil {
+		return session{}, fmt.Errorf("couldn't create refresh token in DB: %w", err)
+	}
+
+	accessToken, err := cfg.makeSessionJWT(r.Context(), rt)
+	if err != nil {
+		return session{}, fmt.Errorf("couldn't create JWT token: %w", err)
+	}
+
+	return session{
+		id:           rt.ID,
+		accessToken:  accessToken,
+		refreshToken: refreshToken,
+	}, nil
+}
+
+// makeSessionJWT issues an access token for the session of rt. Tokens of
+// first-party sessions carry the user's current role; those of OAuth
+// clients carry the client and its scopes instead.
+func (cfg *apiConfig) makeSessionJWT(ctx context.Context, rt database.RefreshToken) (string, error) {
+	params := auth.AccessTokenParams{
+		UserID:    rt.UserID,
+		SessionID: rt.ID,
+		ClientID:  rt.ClientID.UUID,
+		Scopes:    rt.Scopes,
+		ExpiresIn: accessTokenExpiry,
+	}
+	if !rt.ClientID.Valid {
+		user, err := cfg.db.GetUserByID(ctx, rt.UserID)
+		if err != nil {
+			return "", err
+		}
+		params.Role = user.Role
+	}
+
+	return auth.MakeAccessToken(params, cfg.jwtKeys)
+}
+
+// revokeAllSessions signs the user out everywhere: all refresh tokens are
+// revoked and all access tokens issued so far stop being accepted.
+func (cfg *apiConfig) revokeAllSessions(ctx context.Context, userID uuid.UUID) error {
+	if err := cfg.db.RevokeAllRefreshTokens(ctx, userID); err != nil {
+		return err
+	}
+
+	return revokeUserAccessTokens(ctx, cfg.db, userID)
+}
+
+// getActiveRefreshToken looks a refresh token up by its keyed hash. Revoked
+// and expired tokens are never returned.
+func (cfg *apiConfig) getActiveRefreshToken(ctx context.Context, refreshToken string) (database.RefreshToken, error) {
+	return cfg.db.GetActiveRefreshToken(ctx, auth.HashToken(refreshToken, cfg.tokenHashKey))
+}
+
+// hashLegacyRefreshTokens replaces refresh tokens stored in plaintext before
+// hashing was introduced with their keyed hash, so existing sessions survive
+// the upgrade.
+func (cfg *apiConfig) hashLegacyRefreshTokens(ctx context.Context) error {
+	legacyTokens, err := cfg.db.GetLegacyRefreshTokens(ctx)
+	if err != nil {
+		return fmt.Errorf("couldn't get legacy refresh tokens: %w", err)
+	}
+
+	for _, rt := range legacyTokens {
+		params := database.HashLegacyRefreshTokenParams{
+			TokenHash: auth.HashToken(rt.Token.String, cfg.tokenHashKey),
+			Token:     rt.Token,
+		}
+		if err := cfg.db.HashLegacyRefreshToken(ctx, params); err != nil {
+			return fmt.Errorf("couldn't hash legacy refresh token: %w", err)
+		}
+	}
+
+	return nil
+}
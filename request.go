@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"regexp"
+
+	"github.com/google/uuid"
+)
+
+// clientIP returns the address of the peer that sent the request. Forwarding
+// headers are ignored as they can be set by any client.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+const requestIDHeader = "X-Request-ID"
+
+// requestIDPattern limits the request IDs accepted from clients to ones
+// that are safe to log and echo.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+type requestIDContextKey struct{}
+
+// middlewareRequestID gives every request an ID, taken from the request ID
+// header if the client or a proxy set a sane one, and echoes it in the
+// response so problems can be traced through the logs and audit log.
+func middlewareRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(requestIDHeader)
+		if !requestIDPattern.MatchString(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		w.Header().Set(requestIDHeader, requestID)
+		ctx := context.WithValue(r.Context(), requestIDContextKey{}, requestID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func requestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDContextKey{}).(string)
+	return requestID
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+const (
+	roleUser      = "user"
+	roleModerator = "moderator"
+	roleAdmin     = "admin"
+)
+
+// roleRanks orders the roles; each role can do everything the roles below
+// it can.
+var roleRanks = map[string]int{
+	roleUser:      0,
+	roleModerator: 1,
+	roleAdmin:     2,
+}
+
+func validateRole(role string) error {
+	if _, ok := roleRanks[role]; !ok {
+		return fmt.Errorf("unknown role %q", role)
+	}
+	return nil
+}
+
+// hasRole reports whether role grants at least the rights of required.
+// Unknown roles grant nothing.
+func hasRole(role, required string) bool {
+	rank, ok := roleRanks[role]
+	return ok && rank >= roleRanks[required]
+}
+
+// lowerRole returns whichever of two roles grants less.
+func lowerRole(a, b string) string {
+	if hasRole(a, b) {
+		return b
+	}
+	return a
+}
+
+type principalContextKey struct{}
+
+// principalFromContext returns the caller stored by middlewareRequireRole.
+func principalFromContext(ctx context.Context) principal {
+	p, _ := ctx.Value(principalContextKey{}).(principal)
+	return p
+}
+
+// middlewareRequireRole only lets callers with at least role through to
+// next, which can get the caller with principalFromContext. Only the
+// user's own sessions carry a role, so personal access tokens and OAuth
+// clients are always turned away.
+func (cfg *apiConfig) middlewareRequireRole(role string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, ok := cfg.authenticate(w, r, "")
+		if !ok {
+			return
+		}
+		if !hasRole(p.role, role) {
+			respondWithError(w, http.StatusForbidden, fmt.Sprintf("%s role required", role))
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), principalContextKey{}, p)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Scopes a personal access token can be granted. Sessions have all of them.
+const (
+	scopeChirpsRead   = "chirps:read"
+	scopeChirpsWrite  = "chirps:write"
+	scopeProfileWrite = "profile:write"
+	scopeWebhooks     = "webhooks"
+)
+
+var tokenScopes = []string{scopeChirpsRead, scopeChirpsWrite, scopeProfileWrite, scopeWebhooks}
+
+// impliedScopes lists the scopes granted along with another one.
+var impliedScopes = map[string][]string{
+	scopeChirpsWrite: {scopeChirpsRead},
+}
+
+// validateScopes checks that scopes is a non-empty list of known scopes and
+// returns it sorted without duplicates.
+func validateScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+
+	for _, scope := range scopes {
+		if !slices.Contains(tokenScopes, scope) {
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+
+	scopes = slices.Clone(scopes)
+	slices.Sort(scopes)
+	return slices.Compact(scopes), nil
+}
+
+// hasScope reports whether granted includes scope, directly or implied by
+// another granted scope.
+func hasScope(granted []string, scope string) bool {
+	for _, g := range granted {
+		if g == scope || slices.Contains(impliedScopes[g], scope) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,30 @@
+-- name: CreateAuditEvent :exec
+INSERT INTO audit_events (id, created_at, user_id, actor_id, event_type, ip_address, user_agent, request_id, details)
+VALUES (
+  gen_random_uuid(),
+  NOW(),
+  $1,
+  $2,
+  $3,
+  $4,
+  $5,
+  $6,
+  $7
+);
+
+-- name: GetAuditEvents :many
+SELECT * FROM audit_events
+WHERE (sqlc.narg(user_id)::uuid IS NULL OR user_id = sqlc.narg(user_id))
+  AND (sqlc.narg(actor_id)::uuid IS NULL OR actor_id = sqlc.narg(actor_id))
+  AND (sqlc.narg(event_type)::text IS NULL OR event_type = sqlc.narg(event_type))
+  AND (sqlc.narg(ip_address)::text IS NULL OR ip_address = sqlc.narg(ip_address))
+  AND (sqlc.narg(since)::timestamp IS NULL OR created_at >= sqlc.narg(since))
+  AND (sqlc.narg(until)::timestamp IS NULL OR created_at < sqlc.narg(until))
+ORDER BY created_at DESC
+LIMIT sqlc.arg(max_results);
+
+-- name: GetAuditEventsForUser :many
+SELECT * FROM audit_events
+WHERE user_id = $1
+ORDER BY created_at DESC
+LIMIT $2;
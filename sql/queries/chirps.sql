@@ -11,16 +11,29 @@ RETURNING *;
 
 -- name: GetChirps :many
 SELECT
-  *
+  chirps.*
 FROM chirps
-ORDER BY created_at ASC;
+JOIN users ON users.id = chirps.user_id
+WHERE users.deletion_scheduled_for IS NULL
+  AND users.status <> 'banned'
+  AND NOT (users.status = 'suspended' AND users.suspended_until > NOW())
+ORDER BY chirps.created_at ASC;
 
 -- name: GetChirp :one
 SELECT
-  *
+  chirps.*
 FROM chirps
-WHERE id = $1;
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.id = $1
+  AND users.deletion_scheduled_for IS NULL
+  AND users.status <> 'banned'
+  AND NOT (users.status = 'suspended' AND users.suspended_until > NOW());
 
 -- name: DeleteChirp :exec
 DELETE FROM chirps
 WHERE id = $1;
+
+-- name: GetChirpsForUser :many
+SELECT * FROM chirps
+WHERE user_id = $1
+ORDER BY created_at ASC;
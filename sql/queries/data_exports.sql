@@ -0,0 +1,61 @@
+-- name: CreateDataExport :one
+INSERT INTO data_exports (id, user_id, status, created_at)
+VALUES (
+  gen_random_uuid(),
+  $1,
+  'pending',
+  NOW()
+)
+ON CONFLICT (user_id) WHERE status = 'pending' DO NOTHING
+RETURNING id, user_id, status, error, claimed_until, created_at, completed_at, expires_at;
+
+-- name: GetPendingDataExportForUser :one
+SELECT id, user_id, status, error, claimed_until, created_at, completed_at, expires_at FROM data_exports
+WHERE user_id = $1
+  AND status = 'pending';
+
+-- name: GetDataExportForUser :one
+SELECT id, user_id, status, error, claimed_until, created_at, completed_at, expires_at FROM data_exports
+WHERE id = $1
+  AND user_id = $2;
+
+-- name: GetReadyDataExport :one
+SELECT * FROM data_exports
+WHERE id = $1
+  AND status = 'ready'
+  AND expires_at > NOW();
+
+-- name: ClaimPendingDataExport :one
+UPDATE data_exports
+SET claimed_until = NOW() + make_interval(secs => sqlc.arg(lease_seconds)::int)
+WHERE id = (
+  SELECT id FROM data_exports
+  WHERE status = 'pending'
+    AND (claimed_until IS NULL OR claimed_until <= NOW())
+  ORDER BY created_at
+  LIMIT 1
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CompleteDataExport :exec
+UPDATE data_exports
+SET status = 'ready',
+    archive = $2,
+    claimed_until = NULL,
+    completed_at = NOW(),
+    expires_at = NOW() + INTERVAL '7 days'
+WHERE id = $1;
+
+-- name: FailDataExport :exec
+UPDATE data_exports
+SET status = 'failed',
+    error = $2,
+    claimed_until = NULL,
+    completed_at = NOW(),
+    expires_at = NOW() + INTERVAL '7 days'
+WHERE id = $1;
+
+-- name: DeleteExpiredDataExports :exec
+DELETE FROM data_exports
+WHERE expires_at <= NOW();
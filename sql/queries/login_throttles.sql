@@ -0,0 +1,32 @@
+-- name: GetLoginThrottle :one
+SELECT * FROM login_throttles
+WHERE throttle_key = $1;
+
+-- name: RecordLoginFailure :one
+INSERT INTO login_throttles (throttle_key, failures, last_failure_at)
+VALUES (
+  sqlc.arg(throttle_key),
+  1,
+  sqlc.arg(failed_at)
+)
+ON CONFLICT (throttle_key) DO UPDATE
+SET failures = CASE
+      WHEN login_throttles.last_failure_at < sqlc.arg(window_start) THEN 1
+      ELSE login_throttles.failures + 1
+    END,
+    last_failure_at = sqlc.arg(failed_at)
+RETURNING *;
+
+-- name: LockLogin :exec
+UPDATE login_throttles
+SET locked_until = sqlc.arg(locked_until)::timestamp
+WHERE throttle_key = sqlc.arg(throttle_key);
+
+-- name: ClearLoginThrottle :exec
+DELETE FROM login_throttles
+WHERE throttle_key = $1;
+
+-- name: DeleteStaleLoginThrottles :exec
+DELETE FROM login_throttles
+WHERE last_failure_at < sqlc.arg(before)
+  AND (locked_until IS NULL OR locked_until < sqlc.arg(before));
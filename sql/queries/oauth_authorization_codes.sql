@@ -0,0 +1,24 @@
+-- name: CreateOAuthAuthorizationCode :exec
+INSERT INTO oauth_authorization_codes (code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, created_at, expires_at)
+VALUES (
+  $1,
+  $2,
+  $3,
+  $4,
+  $5,
+  $6,
+  NOW(),
+  NOW() + INTERVAL '10 minutes'
+);
+
+-- name: UseOAuthAuthorizationCode :one
+UPDATE oauth_authorization_codes
+SET used_at = NOW()
+WHERE code_hash = $1
+  AND used_at IS NULL
+  AND expires_at > NOW()
+RETURNING *;
+
+-- name: DeleteExpiredOAuthAuthorizationCodes :exec
+DELETE FROM oauth_authorization_codes
+WHERE expires_at < NOW();
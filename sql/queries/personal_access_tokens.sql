@@ -0,0 +1,42 @@
+-- name: CreatePersonalAccessToken :one
+INSERT INTO personal_access_tokens (id, user_id, name, token_hash, scopes, created_at, expires_at)
+VALUES (
+  gen_random_uuid(),
+  sqlc.arg(user_id),
+  sqlc.arg(name),
+  sqlc.arg(token_hash),
+  sqlc.arg(scopes)::text[],
+  NOW(),
+  NOW() + make_interval(days => sqlc.arg(expires_in_days)::int)
+)
+RETURNING *;
+
+-- name: GetActivePersonalAccessToken :one
+SELECT * FROM personal_access_tokens
+WHERE token_hash = $1
+  AND revoked_at IS NULL
+  AND expires_at > NOW();
+
+-- name: GetPersonalAccessTokensForUser :many
+SELECT * FROM personal_access_tokens
+WHERE user_id = $1
+  AND revoked_at IS NULL
+ORDER BY created_at DESC;
+
+-- name: TouchPersonalAccessToken :exec
+UPDATE personal_access_tokens
+SET last_used_at = NOW()
+WHERE id = $1;
+
+-- name: RevokePersonalAccessToken :execrows
+UPDATE personal_access_tokens
+SET revoked_at = NOW()
+WHERE id = $1
+  AND user_id = $2
+  AND revoked_at IS NULL;
+
+-- name: RevokeAllPersonalAccessTokens :exec
+UPDATE personal_access_tokens
+SET revoked_at = NOW()
+WHERE user_id = $1
+  AND revoked_at IS NULL;
@@ -1,20 +1,76 @@
 -- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at)
+INSERT INTO refresh_tokens (token_hash, created_at, updated_at, user_id, expires_at, user_agent, ip_address, client_id, scopes)
 VALUES (
   $1,
   NOW(),
   NOW(),
   $2,
-  NOW() + INTERVAL '60 days'
+  NOW() + INTERVAL '60 days',
+  $3,
+  $4,
+  $5,
+  $6
 )
 RETURNING *;
 
--- name: GetUserFromRefreshToken :one
+-- name: GetActiveRefreshToken :one
 SELECT * FROM refresh_tokens
-WHERE token = $1;
+WHERE token_hash = $1
+  AND revoked_at IS NULL
+  AND expires_at > NOW();
+
+-- name: GetActiveRefreshTokensForUser :many
+SELECT * FROM refresh_tokens
+WHERE user_id = $1
+  AND revoked_at IS NULL
+  AND expires_at > NOW()
+ORDER BY created_at DESC;
+
+-- name: TouchRefreshToken :exec
+UPDATE refresh_tokens
+SET last_used_at = NOW()
+WHERE id = $1;
 
 -- name: RevokeRefreshToken :exec
 UPDATE refresh_tokens
 SET revoked_at = NOW(),
     updated_at = NOW()
-WHERE token = $1;
+WHERE token_hash = $1;
+
+-- name: RevokeRefreshTokenByID :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+  AND user_id = $2
+  AND revoked_at IS NULL;
+
+-- name: RevokeOtherRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE user_id = $1
+  AND id <> $2
+  AND revoked_at IS NULL;
+
+-- name: RevokeAllRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE user_id = $1
+  AND revoked_at IS NULL;
+
+-- name: GetLegacyRefreshTokens :many
+SELECT * FROM refresh_tokens
+WHERE token IS NOT NULL;
+
+-- name: HashLegacyRefreshToken :exec
+UPDATE refresh_tokens
+SET token_hash = $1,
+    token = NULL
+WHERE token = $2;
+
+-- name: GetRefreshTokensForUser :many
+SELECT * FROM refresh_tokens
+WHERE user_id = $1
+ORDER BY created_at DESC;
@@ -0,0 +1,19 @@
+-- name: RevokeAccessToken :execrows
+INSERT INTO revoked_access_tokens (jti, user_id, revoked_at, expires_at)
+VALUES (
+  $1,
+  $2,
+  NOW(),
+  $3
+)
+ON CONFLICT (jti) DO NOTHING;
+
+-- name: IsAccessTokenRevoked :one
+SELECT EXISTS (
+  SELECT 1 FROM revoked_access_tokens
+  WHERE jti = $1
+);
+
+-- name: DeleteExpiredRevokedAccessTokens :exec
+DELETE FROM revoked_access_tokens
+WHERE expires_at < $1;
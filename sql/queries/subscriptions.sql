@@ -0,0 +1,54 @@
+-- name: ActivateSubscription :one
+INSERT INTO subscriptions (id, user_id, plan, status, current_period_end, created_at, updated_at)
+VALUES (
+  gen_random_uuid(),
+  $1,
+  $2,
+  'active',
+  $3,
+  NOW(),
+  NOW()
+)
+ON CONFLICT (user_id) DO UPDATE
+SET plan = EXCLUDED.plan,
+    status = 'active',
+    current_period_end = EXCLUDED.current_period_end,
+    cancel_at_period_end = false,
+    canceled_at = NULL,
+    grace_period_ends_at = NULL,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: GetSubscriptionForUser :one
+SELECT * FROM subscriptions
+WHERE user_id = $1;
+
+-- name: CancelSubscription :execrows
+UPDATE subscriptions
+SET cancel_at_period_end = true,
+    canceled_at = NOW(),
+    current_period_end = COALESCE(current_period_end, NOW()),
+    updated_at = NOW()
+WHERE user_id = $1
+  AND status IN ('active', 'past_due');
+
+-- name: MarkSubscriptionPastDue :execrows
+UPDATE subscriptions
+SET status = 'past_due',
+    grace_period_ends_at = COALESCE(grace_period_ends_at, NOW() + make_interval(days => sqlc.arg(grace_period_days)::int)),
+    updated_at = NOW()
+WHERE user_id = sqlc.arg(user_id)
+  AND status IN ('active', 'past_due');
+
+-- name: RefundSubscription :execrows
+UPDATE subscriptions
+SET status = 'refunded', canceled_at = COALESCE(canceled_at, NOW()), updated_at = NOW()
+WHERE user_id = $1
+  AND status <> 'refunded';
+
+-- name: ExpireLapsedSubscriptions :many
+UPDATE subscriptions
+SET status = 'expired', updated_at = NOW()
+WHERE (status = 'active' AND current_period_end < NOW())
+   OR (status = 'past_due' AND GREATEST(current_period_end, grace_period_ends_at) < NOW())
+RETURNING user_id;
@@ -16,13 +16,163 @@ DELETE FROM users;
 SELECT * FROM users
 WHERE email = $1;
 
--- name: UpdateUser :exec
-UPDATE users
-SET email = $1,
-    hashed_password = $2
-WHERE id = $3;
-
 -- name: UpgradeUserToChirpyRed :exec
 UPDATE users
 SET is_chirpy_red = true
 WHERE id = $1;
+
+-- name: DowngradeUserFromChirpyRed :exec
+UPDATE users
+SET is_chirpy_red = false
+WHERE id = $1;
+
+-- name: RevokeUserAccessTokens :exec
+UPDATE users
+SET tokens_revoked_before = sqlc.arg(tokens_revoked_before)::timestamp
+WHERE id = sqlc.arg(id);
+
+-- name: SetUserTOTPSecret :exec
+UPDATE users
+SET totp_secret = sqlc.arg(totp_secret)::text,
+    totp_enabled_at = NULL,
+    totp_last_step = 0
+WHERE id = sqlc.arg(id);
+
+-- name: EnableUserTOTP :exec
+UPDATE users
+SET totp_enabled_at = NOW(),
+    totp_last_step = $2
+WHERE id = $1;
+
+-- name: UseUserTOTPStep :execrows
+UPDATE users
+SET totp_last_step = $2
+WHERE id = $1
+  AND totp_last_step < $2;
+
+-- name: DisableUserTOTP :exec
+UPDATE users
+SET totp_secret = NULL,
+    totp_enabled_at = NULL,
+    totp_last_step = 0
+WHERE id = $1;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET hashed_password = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetUserPendingEmail :exec
+UPDATE users
+SET pending_email = sqlc.arg(pending_email)::text,
+    updated_at = NOW()
+WHERE id = sqlc.arg(id);
+
+-- name: VerifyUserEmail :one
+UPDATE users
+SET email = sqlc.arg(email),
+    email_verified_at = NOW(),
+    pending_email = CASE WHEN pending_email = sqlc.arg(email) THEN NULL ELSE pending_email END,
+    updated_at = NOW()
+WHERE id = sqlc.arg(id)
+  AND (email = sqlc.arg(email) OR pending_email = sqlc.arg(email))
+RETURNING *;
+
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE id = $1;
+
+-- name: SetUserRole :exec
+UPDATE users
+SET role = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: CountUsersWithRole :one
+SELECT COUNT(*) FROM users
+WHERE role = $1;
+
+-- name: ScheduleUserDeletion :one
+UPDATE users
+SET deletion_scheduled_for = NOW() + make_interval(days => sqlc.arg(grace_period_days)::int),
+    updated_at = NOW()
+WHERE id = sqlc.arg(id)
+RETURNING deletion_scheduled_for::timestamp;
+
+-- name: CancelUserDeletion :execrows
+UPDATE users
+SET deletion_scheduled_for = NULL,
+    updated_at = NOW()
+WHERE id = $1
+  AND deletion_scheduled_for IS NOT NULL;
+
+-- name: DeleteUsersScheduledForDeletion :many
+DELETE FROM users
+WHERE deletion_scheduled_for <= NOW()
+RETURNING id;
+
+-- name: SearchUsers :many
+SELECT * FROM users
+WHERE (sqlc.narg(id)::uuid IS NULL OR id = sqlc.narg(id))
+  AND (sqlc.narg(email)::text IS NULL OR email ILIKE '%' || sqlc.narg(email) || '%')
+  AND (sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status))
+ORDER BY created_at DESC
+LIMIT sqlc.arg(max_results);
+
+-- name: GetUserActivity :one
+SELECT
+  (SELECT COUNT(*) FROM chirps WHERE chirps.user_id = $1) AS chirps,
+  (SELECT COUNT(*) FROM refresh_tokens
+   WHERE refresh_tokens.user_id = $1
+     AND revoked_at IS NULL
+     AND expires_at > NOW()) AS active_sessions,
+  (SELECT COUNT(*) FROM personal_access_tokens
+   WHERE personal_access_tokens.user_id = $1
+     AND revoked_at IS NULL
+     AND expires_at > NOW()) AS personal_access_tokens,
+  (SELECT COUNT(*) FROM webhook_endpoints WHERE webhook_endpoints.user_id = $1) AS webhook_endpoints,
+  (SELECT MAX(created_at) FROM refresh_tokens WHERE refresh_tokens.user_id = $1)::timestamp AS last_login_at;
+
+-- name: SuspendUser :one
+UPDATE users
+SET status = 'suspended',
+    suspended_until = $2,
+    suspension_reason = $3,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: UnsuspendUser :one
+UPDATE users
+SET status = 'active',
+    suspended_until = NULL,
+    suspension_reason = '',
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: BanUser :one
+UPDATE users
+SET status = 'banned',
+    suspended_until = NULL,
+    suspension_reason = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: GetPlaintextTOTPSecrets :many
+SELECT id, totp_secret::text FROM users
+WHERE totp_secret IS NOT NULL
+  AND totp_secret NOT LIKE 'enc:%';
+
+-- name: EncryptUserTOTPSecret :exec
+UPDATE users
+SET totp_secret = sqlc.arg(encrypted_secret)::text
+WHERE id = sqlc.arg(id)
+  AND totp_secret = sqlc.arg(plaintext_secret)::text;
+
+-- name: LockUsersWithRole :many
+SELECT id FROM users
+WHERE role = $1
+FOR UPDATE;
@@ -0,0 +1,63 @@
+-- name: EnqueueWebhookDeliveries :exec
+INSERT INTO webhook_deliveries (id, endpoint_id, event_id, event_type, payload, status, next_attempt_at, created_at)
+SELECT gen_random_uuid(), id, sqlc.arg(event_id), sqlc.arg(event_type)::text, sqlc.arg(payload), 'pending', NOW(), NOW()
+FROM webhook_endpoints
+WHERE user_id = sqlc.arg(user_id)
+  AND sqlc.arg(event_type)::text = ANY(event_types);
+
+-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries (id, endpoint_id, event_id, event_type, payload, status, next_attempt_at, created_at)
+VALUES (
+  gen_random_uuid(),
+  $1,
+  $2,
+  $3,
+  $4,
+  'pending',
+  NOW(),
+  NOW()
+)
+RETURNING *;
+
+-- name: ClaimDueWebhookDeliveries :many
+WITH claimed AS (
+  UPDATE webhook_deliveries
+  SET attempts = attempts + 1,
+      next_attempt_at = NOW() + make_interval(secs => sqlc.arg(lease_seconds)::int)
+  WHERE id IN (
+    SELECT id FROM webhook_deliveries
+    WHERE status = 'pending'
+      AND next_attempt_at <= NOW()
+    ORDER BY next_attempt_at
+    LIMIT sqlc.arg(max_deliveries)
+    FOR UPDATE SKIP LOCKED
+  )
+  RETURNING id, endpoint_id, event_type, payload, attempts
+)
+SELECT claimed.id, claimed.event_type, claimed.payload, claimed.attempts, webhook_endpoints.url, webhook_endpoints.secret
+FROM claimed
+JOIN webhook_endpoints ON webhook_endpoints.id = claimed.endpoint_id;
+
+-- name: MarkWebhookDeliveryDelivered :exec
+UPDATE webhook_deliveries
+SET status = 'delivered',
+    last_attempt_at = NOW(),
+    last_status_code = $2,
+    last_error = '',
+    delivered_at = NOW()
+WHERE id = $1;
+
+-- name: MarkWebhookDeliveryFailed :exec
+UPDATE webhook_deliveries
+SET status = CASE WHEN sqlc.arg(dead)::bool THEN 'dead' ELSE 'pending' END,
+    last_attempt_at = NOW(),
+    last_status_code = sqlc.narg(last_status_code),
+    last_error = sqlc.arg(last_error),
+    next_attempt_at = NOW() + make_interval(secs => sqlc.arg(retry_in_seconds)::int)
+WHERE id = sqlc.arg(id);
+
+-- name: GetWebhookDeliveriesForEndpoint :many
+SELECT * FROM webhook_deliveries
+WHERE endpoint_id = $1
+ORDER BY created_at DESC
+LIMIT $2;
@@ -0,0 +1,28 @@
+-- name: CreateWebhookEndpoint :one
+INSERT INTO webhook_endpoints (id, user_id, client_id, url, secret, event_types, created_at, updated_at)
+VALUES (
+  gen_random_uuid(),
+  sqlc.arg(user_id),
+  sqlc.arg(client_id),
+  sqlc.arg(url),
+  sqlc.arg(secret),
+  sqlc.arg(event_types)::text[],
+  NOW(),
+  NOW()
+)
+RETURNING *;
+
+-- name: GetWebhookEndpointsForUser :many
+SELECT * FROM webhook_endpoints
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+-- name: GetWebhookEndpointForUser :one
+SELECT * FROM webhook_endpoints
+WHERE id = $1
+  AND user_id = $2;
+
+-- name: DeleteWebhookEndpoint :execrows
+DELETE FROM webhook_endpoints
+WHERE id = $1
+  AND user_id = $2;
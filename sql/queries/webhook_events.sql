@@ -0,0 +1,52 @@
+-- name: CreateWebhookEvent :one
+INSERT INTO webhook_events (id, provider, event_id, event_type, payload, status, claimed_until, received_at)
+VALUES (
+  gen_random_uuid(),
+  sqlc.arg(provider),
+  sqlc.arg(event_id),
+  sqlc.arg(event_type),
+  sqlc.arg(payload),
+  'processing',
+  NOW() + make_interval(secs => sqlc.arg(lease_seconds)::int),
+  NOW()
+)
+ON CONFLICT (provider, event_id) DO NOTHING
+RETURNING *;
+
+-- name: RetryFailedWebhookEvent :one
+UPDATE webhook_events
+SET status = 'processing',
+    error = '',
+    attempts = attempts + 1,
+    claimed_until = NOW() + make_interval(secs => sqlc.arg(lease_seconds)::int)
+WHERE provider = sqlc.arg(provider)
+  AND event_id = sqlc.arg(event_id)
+  AND (status = 'failed' OR (status = 'processing' AND (claimed_until IS NULL OR claimed_until <= NOW())))
+RETURNING *;
+
+-- name: ReplayWebhookEvent :one
+UPDATE webhook_events
+SET status = 'processing',
+    error = '',
+    attempts = attempts + 1,
+    claimed_until = NOW() + make_interval(secs => sqlc.arg(lease_seconds)::int)
+WHERE id = sqlc.arg(id)
+  AND (status = 'failed' OR (status = 'processing' AND (claimed_until IS NULL OR claimed_until <= NOW())))
+RETURNING *;
+
+-- name: FinishWebhookEvent :one
+UPDATE webhook_events
+SET status = $2, error = $3, claimed_until = NULL, processed_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: GetWebhookEvent :one
+SELECT * FROM webhook_events
+WHERE id = $1;
+
+-- name: GetWebhookEvents :many
+SELECT * FROM webhook_events
+WHERE (sqlc.narg(provider)::text IS NULL OR provider = sqlc.narg(provider))
+  AND (sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status))
+ORDER BY received_at DESC
+LIMIT sqlc.arg(max_results);
@@ -0,0 +1,28 @@
+-- +goose Up
+ALTER TABLE refresh_tokens
+ADD COLUMN token_hash TEXT;
+
+-- Existing rows get a placeholder that can never match a real hash. The
+-- server replaces it with the keyed hash of the plaintext token on startup.
+UPDATE refresh_tokens
+SET token_hash = 'legacy:' || token;
+
+ALTER TABLE refresh_tokens
+DROP CONSTRAINT refresh_tokens_pkey;
+
+ALTER TABLE refresh_tokens
+ALTER COLUMN token DROP NOT NULL,
+ALTER COLUMN token_hash SET NOT NULL,
+ADD PRIMARY KEY (token_hash);
+
+-- +goose Down
+DELETE FROM refresh_tokens
+WHERE token IS NULL;
+
+ALTER TABLE refresh_tokens
+DROP CONSTRAINT refresh_tokens_pkey;
+
+ALTER TABLE refresh_tokens
+ALTER COLUMN token SET NOT NULL,
+ADD PRIMARY KEY (token),
+DROP COLUMN token_hash;
@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE refresh_tokens
+ADD COLUMN id UUID UNIQUE NOT NULL DEFAULT gen_random_uuid(),
+ADD COLUMN last_used_at TIMESTAMP,
+ADD COLUMN user_agent TEXT NOT NULL DEFAULT '',
+ADD COLUMN ip_address TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE refresh_tokens
+DROP COLUMN id,
+DROP COLUMN last_used_at,
+DROP COLUMN user_agent,
+DROP COLUMN ip_address;
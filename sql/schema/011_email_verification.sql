@@ -0,0 +1,24 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN email_verified_at TIMESTAMP,
+ADD COLUMN pending_email TEXT;
+
+-- Accounts created before verification existed are trusted as they are.
+UPDATE users
+SET email_verified_at = created_at;
+
+CREATE TABLE email_verification_tokens (
+  token_hash TEXT PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  email TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  expires_at TIMESTAMP NOT NULL,
+  used_at TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE email_verification_tokens;
+
+ALTER TABLE users
+DROP COLUMN email_verified_at,
+DROP COLUMN pending_email;
@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE login_throttles (
+  throttle_key TEXT PRIMARY KEY,
+  failures INTEGER NOT NULL,
+  last_failure_at TIMESTAMP NOT NULL,
+  locked_until TIMESTAMP
+);
+
+CREATE TABLE security_events (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  user_id UUID,
+  event_type TEXT NOT NULL,
+  ip_address TEXT NOT NULL,
+  user_agent TEXT NOT NULL,
+  details JSONB NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX security_events_user_id_idx ON security_events (user_id, created_at);
+
+-- +goose Down
+DROP TABLE security_events;
+DROP TABLE login_throttles;
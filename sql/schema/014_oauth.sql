@@ -0,0 +1,34 @@
+-- +goose Up
+CREATE TABLE oauth_clients (
+  id UUID PRIMARY KEY,
+  owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  secret_hash TEXT,
+  redirect_uris TEXT[] NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE oauth_authorization_codes (
+  code_hash TEXT PRIMARY KEY,
+  client_id UUID NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  redirect_uri TEXT NOT NULL,
+  scopes TEXT[] NOT NULL,
+  code_challenge TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  expires_at TIMESTAMP NOT NULL,
+  used_at TIMESTAMP
+);
+
+ALTER TABLE refresh_tokens
+ADD COLUMN client_id UUID REFERENCES oauth_clients(id) ON DELETE CASCADE,
+ADD COLUMN scopes TEXT[] NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE refresh_tokens
+DROP COLUMN client_id,
+DROP COLUMN scopes;
+
+DROP TABLE oauth_authorization_codes;
+DROP TABLE oauth_clients;
@@ -0,0 +1,36 @@
+-- +goose Up
+ALTER TABLE security_events RENAME TO audit_events;
+ALTER INDEX security_events_user_id_idx RENAME TO audit_events_user_id_idx;
+
+ALTER TABLE audit_events
+ADD COLUMN actor_id UUID,
+ADD COLUMN request_id TEXT NOT NULL DEFAULT '';
+
+UPDATE audit_events SET actor_id = user_id;
+
+CREATE INDEX audit_events_created_at_idx ON audit_events (created_at);
+
+-- +goose StatementBegin
+CREATE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER audit_events_append_only
+BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_events
+FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only();
+
+-- +goose Down
+DROP TRIGGER audit_events_append_only ON audit_events;
+DROP FUNCTION audit_events_append_only();
+
+DROP INDEX audit_events_created_at_idx;
+
+ALTER TABLE audit_events
+DROP COLUMN actor_id,
+DROP COLUMN request_id;
+
+ALTER INDEX audit_events_user_id_idx RENAME TO security_events_user_id_idx;
+ALTER TABLE audit_events RENAME TO security_events;
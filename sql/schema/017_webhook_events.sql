@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE webhook_events (
+  id UUID PRIMARY KEY,
+  provider TEXT NOT NULL,
+  event_id TEXT NOT NULL,
+  event_type TEXT NOT NULL,
+  payload JSONB NOT NULL,
+  status TEXT NOT NULL,
+  error TEXT NOT NULL DEFAULT '',
+  attempts INT NOT NULL DEFAULT 1,
+  received_at TIMESTAMP NOT NULL,
+  processed_at TIMESTAMP,
+  UNIQUE (provider, event_id)
+);
+
+CREATE INDEX webhook_events_received_at_idx ON webhook_events (received_at);
+
+-- +goose Down
+DROP TABLE webhook_events;
@@ -0,0 +1,25 @@
+-- +goose Up
+CREATE TABLE subscriptions (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+  plan TEXT NOT NULL,
+  status TEXT NOT NULL CHECK (status IN ('active', 'past_due', 'expired', 'refunded')),
+  current_period_end TIMESTAMP NOT NULL,
+  cancel_at_period_end BOOL NOT NULL DEFAULT false,
+  canceled_at TIMESTAMP,
+  grace_period_ends_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX subscriptions_status_idx ON subscriptions (status, current_period_end);
+
+-- Existing Chirpy Red members were upgraded before renewals were tracked.
+-- Give them a period to get their first renewal through.
+INSERT INTO subscriptions (id, user_id, plan, status, current_period_end, created_at, updated_at)
+SELECT gen_random_uuid(), id, 'red', 'active', NOW() + INTERVAL '30 days', NOW(), NOW()
+FROM users
+WHERE is_chirpy_red;
+
+-- +goose Down
+DROP TABLE subscriptions;
@@ -0,0 +1,12 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN deletion_scheduled_for TIMESTAMP;
+
+CREATE INDEX users_deletion_scheduled_for_idx ON users (deletion_scheduled_for)
+WHERE deletion_scheduled_for IS NOT NULL;
+
+-- +goose Down
+DROP INDEX users_deletion_scheduled_for_idx;
+
+ALTER TABLE users
+DROP COLUMN deletion_scheduled_for;
@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE data_exports (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  status TEXT NOT NULL CHECK (status IN ('pending', 'ready', 'failed')),
+  archive BYTEA,
+  error TEXT NOT NULL DEFAULT '',
+  claimed_until TIMESTAMP,
+  created_at TIMESTAMP NOT NULL,
+  completed_at TIMESTAMP,
+  expires_at TIMESTAMP
+);
+
+-- A user can only have one export being built at a time.
+CREATE UNIQUE INDEX data_exports_pending_user_id_idx ON data_exports (user_id) WHERE status = 'pending';
+CREATE INDEX data_exports_user_id_idx ON data_exports (user_id, created_at);
+
+-- +goose Down
+DROP TABLE data_exports;
@@ -0,0 +1,12 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN status TEXT NOT NULL DEFAULT 'active'
+  CHECK (status IN ('active', 'suspended')),
+ADD COLUMN suspended_until TIMESTAMP,
+ADD COLUMN suspension_reason TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN suspension_reason,
+DROP COLUMN suspended_until,
+DROP COLUMN status;
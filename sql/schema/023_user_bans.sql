@@ -0,0 +1,28 @@
+-- +goose Up
+-- Suspensions now always end; ones that didn't become bans.
+ALTER TABLE users
+DROP CONSTRAINT users_status_check;
+
+UPDATE users
+SET status = 'banned'
+WHERE status = 'suspended'
+  AND suspended_until IS NULL;
+
+ALTER TABLE users
+ADD CONSTRAINT users_status_check
+  CHECK (status IN ('active', 'suspended', 'banned')),
+ADD CONSTRAINT users_suspended_until_check
+  CHECK (status <> 'suspended' OR suspended_until IS NOT NULL);
+
+-- +goose Down
+ALTER TABLE users
+DROP CONSTRAINT users_suspended_until_check,
+DROP CONSTRAINT users_status_check;
+
+UPDATE users
+SET status = 'suspended'
+WHERE status = 'banned';
+
+ALTER TABLE users
+ADD CONSTRAINT users_status_check
+  CHECK (status IN ('active', 'suspended'));
@@ -0,0 +1,9 @@
+-- +goose Up
+-- An event left processing past its lease, say because the server died
+-- halfway, can be claimed again.
+ALTER TABLE webhook_events
+ADD COLUMN claimed_until TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE webhook_events
+DROP COLUMN claimed_until;
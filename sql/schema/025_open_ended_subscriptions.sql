@@ -0,0 +1,24 @@
+-- +goose Up
+-- Subscriptions the provider gave no period end for stay active until
+-- they're canceled or refunded.
+ALTER TABLE subscriptions
+ALTER COLUMN current_period_end DROP NOT NULL;
+
+-- Members carried over from before subscriptions were tracked, and
+-- upgrades without a period end, were given a made-up 30 day period from
+-- when they were last activated. Make them open-ended instead.
+UPDATE subscriptions
+SET current_period_end = NULL,
+    updated_at = NOW()
+WHERE status IN ('active', 'past_due')
+  AND NOT cancel_at_period_end
+  AND current_period_end BETWEEN updated_at + INTERVAL '30 days' - INTERVAL '1 minute'
+                             AND updated_at + INTERVAL '30 days' + INTERVAL '1 minute';
+
+-- +goose Down
+UPDATE subscriptions
+SET current_period_end = updated_at + INTERVAL '30 days'
+WHERE current_period_end IS NULL;
+
+ALTER TABLE subscriptions
+ALTER COLUMN current_period_end SET NOT NULL;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- Refresh tokens stored before hashing got a placeholder hash built from
+-- the plaintext token, which left it readable in a second column until the
+-- server next started. Build the placeholders from the row ID instead; the
+-- server still replaces them with the keyed hash on startup.
+UPDATE refresh_tokens
+SET token_hash = 'legacy:' || id::text
+WHERE token IS NOT NULL;
+
+-- +goose Down
+-- Nothing to undo: neither kind of placeholder matches a real hash.
+SELECT 1;
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/M-Sviridov/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// A Chirpy Red subscription is active from the moment it's paid for until
+// the end of its current period, when a renewal starts a new one. If a
+// renewal payment fails it goes past due, and keeps its benefits for a
+// grace period in case the payment goes through after all. The user's
+// is_chirpy_red flag is kept in step with it.
+//
+// Not every provider tells us when a period ends; Polka only sends
+// upgrades and downgrades. A subscription without a period end stays
+// active until it's canceled or refunded, rather than lapsing on a date
+// we made up.
+const (
+	subscriptionActive   = "active"
+	subscriptionPastDue  = "past_due"
+	subscriptionExpired  = "expired"
+	subscriptionRefunded = "refunded"
+
+	defaultSubscriptionPlan      = "red"
+	defaultSubscriptionGraceDays = 7
+)
+
+var errNoSubscription = errors.New("user has no subscription")
+
+// subscriptionGraceDaysFromEnv reads how many days a past due subscription
+// keeps its benefits for.
+func subscriptionGraceDaysFromEnv() (int32, error) {
+	v := os.Getenv("SUBSCRIPTION_GRACE_PERIOD_DAYS")
+	if v == "" {
+		return defaultSubscriptionGraceDays, nil
+	}
+	days, err := strconv.ParseInt(v, 10, 32)
+	if err != nil || days < 0 {
+		return 0, fmt.Errorf("invalid SUBSCRIPTION_GRACE_PERIOD_DAYS %q", v)
+	}
+	return int32(days), nil
+}
+
+// activateSubscription starts or renews a user's subscription, running
+// until periodEnd, or until it's canceled if periodEnd isn't valid.
+func (cfg *apiConfig) activateSubscription(ctx context.Context, userID uuid.UUID, plan string, periodEnd sql.NullTime) error {
+	return cfg.withTx(ctx, func(q *database.Queries) error {
+		subscriptionParams := database.ActivateSubscriptionParams{
+			UserID:           userID,
+			Plan:             plan,
+			CurrentPeriodEnd: periodEnd,
+		}
+		if _, err := q.ActivateSubscription(ctx, subscriptionParams); err != nil {
+			return err
+		}
+		return q.UpgradeUserToChirpyRed(ctx, userID)
+	})
+}
+
+// cancelSubscription stops a subscription from renewing. The user keeps
+// Chirpy Red until the end of the period they paid for; a subscription
+// without a period end has nothing left to run, so it ends straight away.
+func (cfg *apiConfig) cancelSubscription(ctx context.Context, userID uuid.UUID) error {
+	canceled, err := cfg.db.CancelSubscription(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if canceled == 0 {
+		return errNoSubscription
+	}
+	return cfg.expireLapsedSubscriptions(ctx)
+}
+
+// markSubscriptionPastDue starts the grace period of a subscription whose
+// renewal payment failed, unless it's already in one.
+func (cfg *apiConfig) markSubscriptionPastDue(ctx context.Context, userID uuid.UUID) error {
+	pastDueParams := database.MarkSubscriptionPastDueParams{
+		GracePeriodDays: cfg.subscriptionGraceDays,
+		UserID:          userID,
+	}
+	updated, err := cfg.db.MarkSubscriptionPastDue(ctx, pastDueParams)
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return errNoSubscription
+	}
+	return nil
+}
+
+// refundSubscription ends a subscription that was paid back, taking
+// Chirpy Red away straight away.
+func (cfg *apiConfig) refundSubscription(ctx context.Context, userID uuid.UUID) error {
+	return cfg.withTx(ctx, func(q *database.Queries) error {
+		refunded, err := q.RefundSubscription(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if refunded == 0 {
+			return errNoSubscription
+		}
+		return q.DowngradeUserFromChirpyRed(ctx, userID)
+	})
+}
+
+// expireLapsedSubscriptions ends subscriptions whose period, and grace
+// period if they're past due, is over, and takes Chirpy Red away from
+// their users.
+func (cfg *apiConfig) expireLapsedSubscriptions(ctx context.Context) error {
+	return cfg.withTx(ctx, func(q *database.Queries) error {
+		userIDs, err := q.ExpireLapsedSubscriptions(ctx)
+		if err != nil {
+			return err
+		}
+		for _, userID := range userIDs {
+			if err := q.DowngradeUserFromChirpyRed(ctx, userID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
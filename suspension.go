@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/M-Sviridov/chirpy/internal/database"
+)
+
+// Statuses of a user's account. A suspended user is locked out until their
+// suspension runs out or an admin lifts it; a banned user is locked out
+// until an admin lifts the ban. Either way, their chirps are hidden.
+const (
+	userStatusActive    = "active"
+	userStatusSuspended = "suspended"
+	userStatusBanned    = "banned"
+)
+
+// accountLockout returns why user is locked out of their account at now,
+// or "" if they aren't.
+func accountLockout(user database.User, now time.Time) string {
+	switch {
+	case user.Status == userStatusBanned:
+		return "account is banned"
+	case user.Status == userStatusSuspended && now.Before(user.SuspendedUntil.Time):
+		return "account is suspended until " + user.SuspendedUntil.Time.Format(time.RFC3339)
+	}
+	return ""
+}
+
+// rejectLockedOutUser responds with an error and returns true if user is
+// locked out of their account.
+func rejectLockedOutUser(w http.ResponseWriter, user database.User) bool {
+	lockout := accountLockout(user, time.Now().UTC())
+	if lockout == "" {
+		return false
+	}
+
+	respondWithError(w, http.StatusForbidden, lockout)
+	return true
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/M-Sviridov/chirpy/internal/auth"
+	"github.com/M-Sviridov/chirpy/internal/database"
+)
+
+const (
+	twoFactorIssuer          = "Chirpy"
+	twoFactorChallengeExpiry = 5 * time.Minute
+	recoveryCodeCount        = 10
+)
+
+// verifySecondFactor accepts a TOTP code that hasn't been used before or an
+// unused recovery code, consuming it.
+func (cfg *apiConfig) verifySecondFactor(ctx context.Context, user database.User, code string) (bool, error) {
+	if !user.TotpSecret.Valid {
+		return false, nil
+	}
+
+	secret, err := auth.DecryptSecret(cfg.totpKey, user.TotpSecret.String)
+	if err != nil {
+		return false, err
+	}
+
+	if step, ok := auth.ValidateTOTP(secret, code, time.Now()); ok {
+		used, err := cfg.db.UseUserTOTPStep(ctx, database.UseUserTOTPStepParams{
+			ID:           user.ID,
+			TotpLastStep: step,
+		})
+		if err != nil {
+			return false, err
+		}
+		return used == 1, nil
+	}
+
+	used, err := cfg.db.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+		UserID:   user.ID,
+		CodeHash: auth.HashToken(auth.NormalizeRecoveryCode(code), cfg.tokenHashKey),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return used == 1, nil
+}
+
+// encryptLegacyTOTPSecrets encrypts TOTP secrets stored in plaintext before
+// encryption was introduced, so enrolled users keep their authenticators.
+func (cfg *apiConfig) encryptLegacyTOTPSecrets(ctx context.Context) error {
+	legacySecrets, err := cfg.db.GetPlaintextTOTPSecrets(ctx)
+	if err != nil {
+		return fmt.Errorf("couldn't get plaintext TOTP secrets: %w", err)
+	}
+
+	for _, legacy := range legacySecrets {
+		encrypted, err := auth.EncryptSecret(cfg.totpKey, legacy.TotpSecret)
+		if err != nil {
+			return fmt.Errorf("couldn't encrypt TOTP secret: %w", err)
+		}
+		params := database.EncryptUserTOTPSecretParams{
+			EncryptedSecret: encrypted,
+			ID:              legacy.ID,
+			PlaintextSecret: legacy.TotpSecret,
+		}
+		if err := cfg.db.EncryptUserTOTPSecret(ctx, params); err != nil {
+			return fmt.Errorf("couldn't save encrypted TOTP secret: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/M-Sviridov/chirpy/internal/database"
+	"github.com/M-Sviridov/chirpy/internal/payments"
+	"github.com/google/uuid"
+)
+
+// Every incoming webhook is kept in a ledger, keyed on the provider's event
+// ID, so that retried deliveries are recognised and only acknowledged.
+// Deliveries without an event ID can't be recognised, so each is processed.
+const (
+	webhookStatusProcessing = "processing"
+	webhookStatusProcessed  = "processed"
+	webhookStatusIgnored    = "ignored"
+	webhookStatusFailed     = "failed"
+)
+
+// webhookEventLease is how long a claimed event is left to whoever claimed
+// it. An event still processing after that, say because the server died
+// halfway, can be claimed again by a retry or a replay.
+const webhookEventLease = 5 * time.Minute
+
+// errWebhookIgnored is returned for events Chirpy has no use for.
+var errWebhookIgnored = errors.New("event type is not handled")
+
+// claimWebhookEvent records a delivery in the ledger and claims it for
+// processing. A delivery of an event that was already received is only
+// claimed if processing it failed last time or its claim ran out;
+// otherwise ok is false and the delivery should be acknowledged without
+// doing anything.
+func (cfg *apiConfig) claimWebhookEvent(ctx context.Context, params database.CreateWebhookEventParams) (event database.WebhookEvent, ok bool, err error) {
+	params.LeaseSeconds = int32(webhookEventLease.Seconds())
+	event, err = cfg.db.CreateWebhookEvent(ctx, params)
+	if err == nil {
+		return event, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return event, false, err
+	}
+
+	retryParams := database.RetryFailedWebhookEventParams{
+		Provider:     params.Provider,
+		EventID:      params.EventID,
+		LeaseSeconds: params.LeaseSeconds,
+	}
+	event, err = cfg.db.RetryFailedWebhookEvent(ctx, retryParams)
+	if errors.Is(err, sql.ErrNoRows) {
+		return event, false, nil
+	}
+	if err != nil {
+		return event, false, err
+	}
+	return event, true, nil
+}
+
+// processWebhookEvent handles a claimed event and records the outcome in
+// the ledger. The returned error is the handler's, if it failed.
+func (cfg *apiConfig) processWebhookEvent(r *http.Request, event database.WebhookEvent) (database.WebhookEvent, error) {
+	var err error
+	if provider, ok := cfg.paymentProviders[event.Provider]; ok {
+		err = cfg.handlePaymentEvent(r, provider, event)
+	} else {
+		err = fmt.Errorf("unknown payment provider %q", event.Provider)
+	}
+
+	finishParams := database.FinishWebhookEventParams{
+		ID:     event.ID,
+		Status: webhookStatusProcessed,
+	}
+	switch {
+	case errors.Is(err, errWebhookIgnored):
+		finishParams.Status = webhookStatusIgnored
+		err = nil
+	case err != nil:
+		finishParams.Status = webhookStatusFailed
+		finishParams.Error = err.Error()
+	}
+
+	finished, finishErr := cfg.db.FinishWebhookEvent(r.Context(), finishParams)
+	if finishErr != nil {
+		return event, errors.Join(err, finishErr)
+	}
+	return finished, err
+}
+
+// handlePaymentEvent applies a payment provider's event to the
+// subscription of the user it concerns.
+func (cfg *apiConfig) handlePaymentEvent(r *http.Request, provider payments.Provider, event database.WebhookEvent) error {
+	paymentEvent, err := provider.ParseEvent(event.Payload)
+	if err != nil {
+		return err
+	}
+	if paymentEvent.Type == "" {
+		return errWebhookIgnored
+	}
+
+	userID, err := provider.UserID(paymentEvent.CustomerID)
+	if err != nil {
+		return fmt.Errorf("couldn't map customer %q to a user: %w", paymentEvent.CustomerID, err)
+	}
+
+	auditType := auditSubscriptionUpdated
+	switch paymentEvent.Type {
+	case payments.EventSubscriptionActivated, payments.EventSubscriptionRenewed:
+		plan := paymentEvent.Plan
+		if plan == "" {
+			plan = defaultSubscriptionPlan
+		}
+		var periodEnd sql.NullTime
+		if !paymentEvent.CurrentPeriodEnd.IsZero() {
+			periodEnd = sql.NullTime{Time: paymentEvent.CurrentPeriodEnd.UTC(), Valid: true}
+		}
+		err = cfg.activateSubscription(r.Context(), userID, plan, periodEnd)
+		if paymentEvent.Type == payments.EventSubscriptionActivated {
+			auditType = auditUserUpgraded
+		}
+	case payments.EventSubscriptionCanceled:
+		err = cfg.cancelSubscription(r.Context(), userID)
+	case payments.EventPaymentFailed:
+		err = cfg.markSubscriptionPastDue(r.Context(), userID)
+	case payments.EventPaymentRefunded:
+		err = cfg.refundSubscription(r.Context(), userID)
+		auditType = auditUserDowngraded
+	default:
+		return errWebhookIgnored
+	}
+	if err != nil {
+		return fmt.Errorf("couldn't apply %s to subscription: %w", paymentEvent.Type, err)
+	}
+
+	cfg.recordAuditEvent(r, auditEvent{
+		eventType: auditType,
+		userID:    uuid.NullUUID{UUID: userID, Valid: true},
+		details: map[string]any{
+			"source":           provider.Name(),
+			"event":            paymentEvent.Type,
+			"webhook_event_id": event.ID,
+		},
+	})
+	return nil
+}